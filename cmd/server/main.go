@@ -8,8 +8,11 @@ import (
 	"os/signal"
 	"rbac/internal/api"
 	"rbac/internal/config"
+	"rbac/internal/domain"
+	"rbac/internal/mail"
 	"rbac/internal/repository/mysql"
 	"rbac/internal/service"
+	"rbac/internal/utils"
 	"syscall"
 	"time"
 
@@ -36,19 +39,113 @@ func main() {
 	userRepo := mysql.NewUserRepository(db)
 	roleRepo := mysql.NewRoleRepository(db)
 	productRepo := mysql.NewProductRepository(db)
+	orgRepo := mysql.NewOrganizationRepository(db)
+	groupRepo := mysql.NewGroupRepository(db)
+	auditRepo := mysql.NewAuditRepository(db)
+	refreshRepo := mysql.NewRefreshTokenRepository(db)
+	revocationRepo := mysql.NewTokenRevocationRepository(db)
+	signingKeyRepo := mysql.NewSigningKeyRepository(db)
+	oauthClientRepo := mysql.NewOAuthClientRepository(db)
+	authCodeRepo := mysql.NewAuthorizationCodeRepository(db)
+	serviceAccountRepo := mysql.NewServiceAccountRepository(db)
+	apiKeyRepo := mysql.NewAPIKeyRepository(db)
+	mfaRepo := mysql.NewMFARepository(db)
+	mfaChallengeRepo := mysql.NewMFAChallengeRepository(db)
+	actionTokenRepo := mysql.NewActionTokenRepository(db)
+	loginFailureRepo := mysql.NewLoginFailureRepository(db)
+	transactor := mysql.NewTransactor(db)
+
+	// Mail delivery
+	var mailer mail.Mailer
+	switch cfg.MailTransport {
+	case "smtp":
+		mailer = mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "file":
+		mailFile, err := os.OpenFile(cfg.MailFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Fatalf("Failed to open mail file: %v", err)
+		}
+		defer mailFile.Close()
+		mailer = mail.NewLogMailer(mailFile)
+	default:
+		mailer = mail.NewLogMailer(os.Stderr)
+	}
 
 	// Service Layer
-	authSvc := service.NewAuthService(userRepo, roleRepo, cfg.JWTSecret, cfg.JWTExpirationInHours)
-	rbacSvc := service.NewRBACService(userRepo)
+	permCache := service.NewPermissionCache(time.Duration(cfg.PermissionCacheTTLSeconds)*time.Second, cfg.PermissionCacheSize)
+	// Deactivation invalidates this instance at once; the TTL bounds other instances
+	statusCache := service.NewUserStatusCache(time.Duration(cfg.PermissionCacheTTLSeconds)*time.Second, cfg.PermissionCacheSize)
+	apiKeyCache := service.NewAPIKeyCache(time.Duration(cfg.PermissionCacheTTLSeconds)*time.Second, cfg.PermissionCacheSize)
+	revocations := service.NewTokenRevocationList(revocationRepo, time.Duration(cfg.TokenRevocationSyncSeconds)*time.Second)
+	// Load existing revocations before serving, so no revoked token slips through at startup
+	if err := revocations.Sync(context.Background()); err != nil {
+		log.Fatalf("Failed to load token revocations: %v", err)
+	}
+	keys := service.NewKeyManager(signingKeyRepo, cfg.TokenSigningAlgorithm,
+		time.Duration(cfg.SigningKeyRotationHours)*time.Hour, time.Duration(cfg.SigningKeyOverlapHours)*time.Hour,
+		time.Duration(cfg.SigningKeyCheckMinutes)*time.Minute, cfg.SigningKeyEncryptionKey)
+	// Every instance needs a signing key before it can issue tokens
+	if err := keys.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	if err := keys.Rotate(context.Background()); err != nil {
+		log.Fatalf("Failed to create signing key: %v", err)
+	}
+	throttle := service.NewLoginThrottle(loginFailureRepo, transactor, auditRepo, service.LoginThrottlePolicy{
+		FreeAttempts:            cfg.LoginFreeAttempts,
+		BackoffBase:             time.Duration(cfg.LoginBackoffBaseSeconds) * time.Second,
+		BackoffMax:              time.Duration(cfg.LoginBackoffMaxSeconds) * time.Second,
+		FailureWindow:           time.Duration(cfg.LoginFailureWindowMinutes) * time.Minute,
+		AccountLockoutThreshold: cfg.LoginAccountLockoutThreshold,
+		IPLockoutThreshold:      cfg.LoginIPLockoutThreshold,
+		LockoutDuration:         time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
+		MFALockoutThreshold:     cfg.MFALockoutThreshold,
+	})
+	resetWindow := time.Duration(cfg.PasswordResetWindowMinutes) * time.Minute
+	resetLimits := service.PasswordResetLimits{
+		PerAddress: service.NewRateLimiter(loginFailureRepo, domain.ThrottleScopeResetEmail, cfg.PasswordResetAddressLimit, resetWindow),
+		PerIP:      service.NewRateLimiter(loginFailureRepo, domain.ThrottleScopeResetIP, cfg.PasswordResetIPLimit, resetWindow),
+	}
+	var breaches service.BreachedPasswordSource
+	if cfg.PasswordBreachFile != "" {
+		breachFile, err := service.OpenHashPrefixFile(cfg.PasswordBreachFile)
+		if err != nil {
+			log.Fatalf("Failed to open breached password file: %v", err)
+		}
+		defer breachFile.Close()
+		breaches = breachFile
+	}
+	passwords := service.NewPasswordPolicy(cfg.PasswordMinLength, utils.PasswordHashParams{
+		Algorithm:         cfg.PasswordHashAlgorithm,
+		BcryptCost:        cfg.BcryptCost,
+		Argon2MemoryKiB:   cfg.Argon2MemoryKiB,
+		Argon2Iterations:  cfg.Argon2Iterations,
+		Argon2Parallelism: cfg.Argon2Parallelism,
+	}, breaches)
+	mfaSvc := service.NewMFAService(mfaRepo, mfaChallengeRepo, userRepo, auditRepo, throttle, cfg.MFAIssuer,
+		time.Duration(cfg.MFAChallengeTTLMinutes)*time.Minute)
+	authSvc := service.NewAuthService(userRepo, roleRepo, orgRepo, refreshRepo, auditRepo, transactor, mfaSvc, statusCache, revocations, throttle, passwords, keys,
+		time.Duration(cfg.AccessTokenTTLMinutes)*time.Minute, time.Duration(cfg.RefreshTokenTTLHours)*time.Hour)
+	rbacSvc := service.NewRBACService(userRepo, productRepo, permCache, cfg.UnverifiedEmailBlockedPermissions)
+	roleSvc := service.NewRoleService(roleRepo, permCache)
+	userSvc := service.NewUserService(userRepo, roleRepo, orgRepo, auditRepo, refreshRepo, apiKeyRepo, permCache, statusCache, apiKeyCache, throttle)
+	groupSvc := service.NewGroupService(groupRepo, roleRepo, orgRepo, permCache)
 	productSvc := service.NewProductService(productRepo)
+	saSvc := service.NewServiceAccountService(userRepo, serviceAccountRepo, orgRepo, auditRepo, transactor, permCache, statusCache, keys,
+		time.Duration(cfg.AccessTokenTTLMinutes)*time.Minute)
+	oidcSvc := service.NewOIDCService(oauthClientRepo, authCodeRepo, userRepo, orgRepo, authSvc, mfaSvc, keys, revocations, cfg.OIDCIssuer,
+		time.Duration(cfg.AccessTokenTTLMinutes)*time.Minute)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, userRepo, orgRepo, rbacSvc, apiKeyCache, statusCache)
+	accountSvc := service.NewAccountService(userRepo, refreshRepo, apiKeyRepo, actionTokenRepo, auditRepo, mailer, statusCache, apiKeyCache, throttle, resetLimits, passwords, keys, cfg.OIDCIssuer,
+		time.Duration(cfg.PasswordResetTTLMinutes)*time.Minute, time.Duration(cfg.EmailVerificationTTLHours)*time.Hour)
 	graphqlSvc := service.NewGraphQLService()
 
 	// API/Handler Layer
-	apiHandler := api.NewAPIHandler(authSvc, rbacSvc, productSvc, graphqlSvc)
+	apiHandler := api.NewAPIHandler(authSvc, rbacSvc, roleSvc, userSvc, groupSvc, productSvc, oidcSvc, saSvc, apiKeySvc, mfaSvc, accountSvc, graphqlSvc)
 
 	// --- 4. Setup Router & Routes ---
 	router := mux.NewRouter()
-	apiHandler.RegisterRoutes(router, cfg.JWTSecret)
+	apiHandler.RegisterRoutes(router)
 
 	// --- 5. Start HTTP Server (with Graceful Shutdown) ---
 	srv := &http.Server{
@@ -64,11 +161,25 @@ func main() {
 		}
 	}()
 
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	roleSweeper := service.NewRoleExpirySweeper(userRepo, auditRepo, permCache, time.Duration(cfg.RoleSweepIntervalMinutes)*time.Minute)
+	go roleSweeper.Run(jobsCtx)
+	// Failure counts are kept for as long as the longest window that counts them
+	failureRetention := max(time.Duration(cfg.LoginFailureWindowMinutes)*time.Minute, resetWindow)
+	recordSweeper := service.NewRecordSweeper(actionTokenRepo, mfaChallengeRepo, loginFailureRepo, failureRetention, time.Duration(cfg.RecordSweepIntervalMinutes)*time.Minute)
+	go recordSweeper.Run(jobsCtx)
+	go revocations.Run(jobsCtx)
+	go keys.Run(jobsCtx)
+
 	// Wait for interrupt signal (Ctrl+C)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Create a context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
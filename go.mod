@@ -10,4 +10,7 @@ require (
 	golang.org/x/crypto v0.43.0
 )
 
-require filippo.io/edwards25519 v1.1.0 // indirect
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+)
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- Account Recovery Handlers ---
+
+// ForgotPasswordHandler mails a password reset token. It answers the same way
+// whether or not the address is registered.
+func (h *APIHandler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	// The client IP is tracked to limit how many emails one client can trigger
+	if err := h.accountSvc.RequestPasswordReset(withEnvironment(r), req.Email); err != nil {
+		respondWithServiceError(w, err, "Failed to request password reset")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the address is registered, a password reset email is on its way",
+	})
+}
+
+// ResetPasswordHandler sets a new password with a token from the reset email
+func (h *APIHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.accountSvc.ResetPassword(r.Context(), req); err != nil {
+		respondWithServiceError(w, err, "Failed to reset password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePasswordHandler replaces the caller's password. All their sessions,
+// including this one, are logged out.
+func (h *APIHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	// The client IP is tracked to throttle guessing of the current password
+	if err := h.accountSvc.ChangePassword(withEnvironment(r), userID, req); err != nil {
+		respondWithServiceError(w, err, "Failed to change password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendVerificationEmailHandler mails the caller a link verifying their address
+func (h *APIHandler) SendVerificationEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.accountSvc.SendVerificationEmail(r.Context(), userID); err != nil {
+		respondWithServiceError(w, err, "Failed to send verification email")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{"message": "Verification email sent"})
+}
+
+// VerifyEmailHandler verifies an address. The token comes from the emailed
+// link's query string (GET) or a JSON body (POST).
+func (h *APIHandler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" && r.Method == http.MethodPost {
+		var req domain.VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		defer r.Body.Close()
+		token = req.Token
+	}
+	if token == "" {
+		respondWithError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	if err := h.accountSvc.VerifyEmail(r.Context(), token); err != nil {
+		respondWithServiceError(w, err, "Failed to verify email address")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Email address verified"})
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+	"strconv"
+)
+
+// --- Admin Handlers ---
+
+// GetEffectiveRolesHandler lists a user's roles in the caller's active organization,
+// showing whether each is direct, group-derived or inherited
+func (h *APIHandler) GetEffectiveRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	roles, err := h.rbacSvc.GetEffectiveRoles(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to load effective roles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, roles)
+}
+
+// AssignUserRoleHandler assigns a role to a user in the caller's active organization.
+// An optional valid_from/valid_until window grants temporary access that expires on its own.
+func (h *APIHandler) AssignUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req domain.AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.userSvc.AssignRole(r.Context(), actorID, userID, req); err != nil {
+		respondWithServiceError(w, err, "Failed to assign role")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchUsersHandler lists members of the caller's active organization,
+// filtered by ?q= (username or email) and paginated by ?page= and ?page_size=
+func (h *APIHandler) SearchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := domain.UserSearch{Query: query.Get("q")}
+	var err error
+	if v := query.Get("page"); v != "" {
+		if search.Page, err = strconv.Atoi(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid page")
+			return
+		}
+	}
+	if v := query.Get("page_size"); v != "" {
+		if search.PageSize, err = strconv.Atoi(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid page_size")
+			return
+		}
+	}
+
+	page, err := h.userSvc.SearchUsers(r.Context(), search)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to search users")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// ListUserRolesHandler lists a user's direct role assignments in the caller's active organization
+func (h *APIHandler) ListUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	roles, err := h.userSvc.ListRoles(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list user roles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, roles)
+}
+
+// RevokeUserRoleHandler removes a direct role assignment in the caller's active organization
+func (h *APIHandler) RevokeUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	roleID, err := parseIDVar(r, "roleID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.userSvc.RevokeRole(r.Context(), actorID, userID, roleID); err != nil {
+		respondWithServiceError(w, err, "Failed to revoke role")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeactivateUserHandler locks a user out, including any tokens they already hold
+func (h *APIHandler) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, false)
+}
+
+// ActivateUserHandler restores a deactivated user
+func (h *APIHandler) ActivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, true)
+}
+
+func (h *APIHandler) setUserActive(w http.ResponseWriter, r *http.Request, active bool) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if active {
+		err = h.userSvc.Activate(r.Context(), actorID, userID)
+	} else {
+		err = h.userSvc.Deactivate(r.Context(), actorID, userID)
+	}
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to update user status")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessionsHandler logs a user out of every session
+func (h *APIHandler) RevokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.userSvc.RevokeSessions(r.Context(), actorID, userID); err != nil {
+		respondWithServiceError(w, err, "Failed to revoke sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockUserHandler lifts a lockout caused by repeated failed logins
+func (h *APIHandler) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.userSvc.Unlock(r.Context(), actorID, userID); err != nil {
+		respondWithServiceError(w, err, "Failed to unlock user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- API Key Handlers ---
+
+// CreateAPIKeyHandler creates an API key for the caller in their active
+// organization. The key is only returned in this response.
+func (h *APIHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	created, err := h.apiKeySvc.Create(r.Context(), userID, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to create API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// ListAPIKeysHandler lists the caller's API keys
+func (h *APIHandler) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	keys, err := h.apiKeySvc.List(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list API keys")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// DeleteAPIKeyHandler revokes one of the caller's API keys
+func (h *APIHandler) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.apiKeySvc.Delete(r.Context(), userID, keyID); err != nil {
+		respondWithServiceError(w, err, "Failed to delete API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,8 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"rbac/internal/domain"
+	"rbac/internal/service"
+	"rbac/internal/utils"
 )
 
 func (h *APIHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
@@ -21,11 +25,21 @@ func (h *APIHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := h.authSvc.Register(r.Context(), req)
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		respondWithServiceError(w, err, "Failed to register")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	// The account is usable without it; the user can ask for another email
+	if err := h.accountSvc.SendVerificationEmail(r.Context(), user.ID); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	respondWithJSON(w, http.StatusCreated, user)
 }
 
@@ -37,11 +51,146 @@ func (h *APIHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	token, err := h.authSvc.Login(r.Context(), req)
+	// The client IP is tracked to throttle password guessing
+	resp, err := h.authSvc.Login(withEnvironment(r), req)
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		respondWithServiceError(w, err, "Failed to log in")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, domain.LoginResponse{Token: token})
-}
\ No newline at end of file
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// MFALoginHandler completes a login for a user enrolled in MFA: the
+// mfa_token from /login and a TOTP or recovery code are exchanged for tokens
+func (h *APIHandler) MFALoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := h.authSvc.CompleteMFALogin(r.Context(), req)
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		respondWithServiceError(w, err, "Failed to complete login")
+		return
+	}
+	switch err {
+	case nil:
+		respondWithJSON(w, http.StatusOK, resp)
+	case service.ErrInvalidMFAChallenge, service.ErrInvalidMFACode, service.ErrAccountDeactivated:
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Failed to complete login")
+	}
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new access and refresh token
+func (h *APIHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := h.authSvc.Refresh(r.Context(), req.RefreshToken)
+	switch err {
+	case nil:
+		respondWithJSON(w, http.StatusOK, resp)
+	case service.ErrInvalidRefreshToken, service.ErrRefreshTokenReused, service.ErrAccountDeactivated:
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	case service.ErrNotOrgMember:
+		respondWithError(w, http.StatusForbidden, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token")
+	}
+}
+
+// LogoutHandler revokes the caller's access token, and the refresh token
+// family too when a refresh_token is given in the body
+func (h *APIHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.LogoutRequest
+	// The body is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	claims, ok := r.Context().Value(ClaimsKey).(*utils.Claims)
+	if !ok {
+		// Authenticated with an API key: there is no session to end
+		respondWithError(w, http.StatusBadRequest, "Logout requires an access token; delete the API key instead")
+		return
+	}
+
+	if err := h.authSvc.Logout(r.Context(), claims, req.RefreshToken); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKSHandler publishes the public signing keys, so other services can verify
+// access tokens offline
+func (h *APIHandler) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	// Short enough that verifiers pick up a rotated key well within the overlap window
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, h.authSvc.JWKS())
+}
+
+// ListOrganizationsHandler lists the organizations the caller belongs to
+func (h *APIHandler) ListOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	orgs, err := h.authSvc.ListOrganizations(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list organizations")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, orgs)
+}
+
+// SwitchOrganizationHandler issues a new token scoped to another organization
+func (h *APIHandler) SwitchOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.SwitchOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	resp, err := h.authSvc.SwitchOrganization(r.Context(), userID, req.OrgID)
+	if err == service.ErrNotOrgMember || err == service.ErrAPIKeyNotAllowed {
+		respondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to switch organization")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- Authorization Handlers ---
+
+// maxBatchPermissions bounds how many permissions one batch check may ask about
+const maxBatchPermissions = 100
+
+// CheckPermissionsHandler tells the caller which of the listed permissions they
+// hold, so a frontend can decide what to render in one round trip
+func (h *APIHandler) CheckPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.CheckPermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if len(req.Permissions) == 0 || len(req.Permissions) > maxBatchPermissions {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Between 1 and %d permissions are required", maxBatchPermissions))
+		return
+	}
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	results, err := h.rbacSvc.CheckPermissions(withEnvironment(r), userID, req.Permissions)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to check permissions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, domain.CheckPermissionsResponse{Results: results})
+}
+
+// ExplainHandler returns a permission decision for any user in the caller's
+// active organization together with its full derivation, so support can see
+// why a request was refused
+func (h *APIHandler) ExplainHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.ExplainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.UserID == 0 || req.Permission == "" {
+		respondWithError(w, http.StatusBadRequest, "user_id and permission are required")
+		return
+	}
+
+	explanation, err := h.rbacSvc.Explain(withEnvironment(r), req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to explain decision")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, explanation)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- Group Handlers ---
+
+// CreateGroupHandler creates a group in the caller's active organization
+func (h *APIHandler) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Group name is required")
+		return
+	}
+
+	group, err := h.groupSvc.CreateGroup(r.Context(), req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to create group")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, group)
+}
+
+// ListGroupsHandler lists the groups of the caller's active organization
+func (h *APIHandler) ListGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groupSvc.ListGroups(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list groups")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, groups)
+}
+
+// AddGroupMemberHandler adds a user to a group
+func (h *APIHandler) AddGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	var req domain.GroupMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.groupSvc.AddMember(r.Context(), groupID, req.UserID); err != nil {
+		respondWithServiceError(w, err, "Failed to add group member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveGroupMemberHandler removes a user from a group
+func (h *APIHandler) RemoveGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+	userID, err := parseIDVar(r, "userID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.groupSvc.RemoveMember(r.Context(), groupID, userID); err != nil {
+		respondWithServiceError(w, err, "Failed to remove group member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignGroupRoleHandler assigns a role to every member of a group
+func (h *APIHandler) AssignGroupRoleHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	var req domain.GroupRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.groupSvc.AssignRole(r.Context(), groupID, req.RoleID); err != nil {
+		respondWithServiceError(w, err, "Failed to assign role to group")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeGroupRoleHandler removes a role from a group
+func (h *APIHandler) RevokeGroupRoleHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+	roleID, err := parseIDVar(r, "roleID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	if err := h.groupSvc.RevokeRole(r.Context(), groupID, roleID); err != nil {
+		respondWithServiceError(w, err, "Failed to revoke role from group")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,16 +2,30 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"rbac/internal/domain"
+	"rbac/internal/repository"
 	"rbac/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
 )
 
 // APIHandler holds all services, acting as our dependency injection container
 type APIHandler struct {
 	authSvc    service.AuthService
 	rbacSvc    service.RBACService
+	roleSvc    service.RoleService
+	userSvc    service.UserService
+	groupSvc   service.GroupService
 	productSvc service.ProductService
+	oidcSvc    service.OIDCService
+	saSvc      service.ServiceAccountService
+	apiKeySvc  service.APIKeyService
+	mfaSvc     service.MFAService
+	accountSvc service.AccountService
 	graphqlSvc service.GraphQLService
 }
 
@@ -19,13 +33,29 @@ type APIHandler struct {
 func NewAPIHandler(
 	authSvc service.AuthService,
 	rbacSvc service.RBACService,
+	roleSvc service.RoleService,
+	userSvc service.UserService,
+	groupSvc service.GroupService,
 	productSvc service.ProductService,
+	oidcSvc service.OIDCService,
+	saSvc service.ServiceAccountService,
+	apiKeySvc service.APIKeyService,
+	mfaSvc service.MFAService,
+	accountSvc service.AccountService,
 	graphqlSvc service.GraphQLService,
 ) *APIHandler {
 	return &APIHandler{
 		authSvc:    authSvc,
 		rbacSvc:    rbacSvc,
+		roleSvc:    roleSvc,
+		userSvc:    userSvc,
+		groupSvc:   groupSvc,
 		productSvc: productSvc,
+		oidcSvc:    oidcSvc,
+		saSvc:      saSvc,
+		apiKeySvc:  apiKeySvc,
+		mfaSvc:     mfaSvc,
+		accountSvc: accountSvc,
 		graphqlSvc: graphqlSvc,
 	}
 }
@@ -48,9 +78,16 @@ func (h *APIHandler) CreateProductHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	product, err := h.productSvc.CreateProduct(r.Context(), req, userID)
+	// Products are created in the caller's active organization
+	orgID, ok := service.OrgIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusForbidden, "No active organization")
+		return
+	}
+
+	product, err := h.productSvc.CreateProduct(r.Context(), req, userID, orgID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create product")
+		respondWithServiceError(w, err, "Failed to create product")
 		return
 	}
 
@@ -63,6 +100,50 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
 
+// respondWithServiceError maps well-known service errors to status codes,
+// falling back to a 500 with a generic message
+func respondWithServiceError(w http.ResponseWriter, err error, fallback string) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		respondWithError(w, http.StatusBadRequest, validationErr.Message)
+		return
+	}
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":      policyErr.Error(),
+			"violations": policyErr.Violations,
+		})
+		return
+	}
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	switch err {
+	case repository.ErrDuplicate, repository.ErrRoleCycle, service.ErrMFAAlreadyEnabled:
+		respondWithError(w, http.StatusConflict, err.Error())
+	case repository.ErrNotFound:
+		respondWithError(w, http.StatusNotFound, "Not found")
+	case service.ErrNoActiveOrg, service.ErrNotOrgMember, service.ErrAPIKeyNotAllowed:
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case service.ErrRateLimited:
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+	case service.ErrInvalidValidity, service.ErrUnknownResourceType, service.ErrInvalidMFACode, service.ErrMFANotEnrolled, service.ErrInvalidActionToken:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// parseIDVar reads a numeric route variable such as {id}
+func parseIDVar(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 	w.Header().Set("Content-Type", "application/json")
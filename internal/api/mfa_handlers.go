@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- MFA Handlers ---
+
+// MFAStatusHandler reports whether the caller has MFA enabled
+func (h *APIHandler) MFAStatusHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	status, err := h.mfaSvc.Status(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to load MFA status")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// BeginTOTPEnrollmentHandler returns a new TOTP secret and its provisioning
+// URI, to be shown as a QR code. MFA is enforced once it is verified.
+func (h *APIHandler) BeginTOTPEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	setup, err := h.mfaSvc.BeginTOTPEnrollment(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to start TOTP enrollment")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, setup)
+}
+
+// ConfirmTOTPEnrollmentHandler enables MFA with a first code from the
+// authenticator and returns the recovery codes
+func (h *APIHandler) ConfirmTOTPEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	codes, err := h.mfaSvc.ConfirmTOTPEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to verify TOTP enrollment")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, codes)
+}
+
+// DisableTOTPHandler turns MFA off; the body must carry a current code
+func (h *APIHandler) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.mfaSvc.DisableTOTP(r.Context(), userID, req.Code); err != nil {
+		respondWithServiceError(w, err, "Failed to disable MFA")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodesHandler replaces the caller's recovery codes; the
+// body must carry a current code
+func (h *APIHandler) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	codes, err := h.mfaSvc.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, codes)
+}
@@ -2,10 +2,15 @@ package api
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"rbac/internal/domain"
+	"rbac/internal/policy"
+	"rbac/internal/repository"
 	"rbac/internal/service"
-	"rbac/internal/utils"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -16,12 +21,20 @@ type CtxKey string
 const (
 	// UserIDKey is the key for user ID in context
 	UserIDKey CtxKey = "userID"
+	// ClaimsKey is the key for the validated token claims in context
+	ClaimsKey CtxKey = "claims"
 )
 
-// AuthMiddleware validates the JWT token
-func AuthMiddleware(jwtSecret string) mux.MiddlewareFunc {
+// AuthMiddleware validates the JWT token or API key and refuses deactivated
+// accounts. API keys are accepted in an X-API-Key header or as a Bearer token.
+func AuthMiddleware(authSvc service.AuthService, apiKeySvc service.APIKeyService) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+				authenticateAPIKey(w, r, next, apiKeySvc, apiKey)
+				return
+			}
+
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
 				http.Error(w, "Authorization header required", http.StatusUnauthorized)
@@ -33,20 +46,101 @@ func AuthMiddleware(jwtSecret string) mux.MiddlewareFunc {
 				http.Error(w, "Invalid token format", http.StatusUnauthorized)
 				return
 			}
+			if strings.HasPrefix(tokenString, domain.APIKeyPrefix) {
+				authenticateAPIKey(w, r, next, apiKeySvc, tokenString)
+				return
+			}
+
+			claims, err := authSvc.Authenticate(r.Context(), tokenString)
+			if err == service.ErrAccountDeactivated {
+				http.Error(w, "Account is deactivated", http.StatusUnauthorized)
+				return
+			}
+			if err == service.ErrTokenRevoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			// Add user ID and active organization to context
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			ctx = service.WithOrgID(ctx, claims.OrgID)
+			if claims.MFA() {
+				ctx = service.WithMFAVerified(ctx, claims.UserID)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientTokenMiddleware accepts only access tokens issued to OIDC clients that
+// were granted scope. AuthMiddleware refuses those tokens, so a client cannot
+// use the first-party API with the token a user gave it.
+func ClientTokenMiddleware(authSvc service.AuthService, scope string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if tokenString == r.Header.Get("Authorization") {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				http.Error(w, "Bearer token required", http.StatusUnauthorized)
+				return
+			}
 
-			claims, err := utils.ValidateToken(tokenString, jwtSecret)
+			claims, err := authSvc.AuthenticateClient(r.Context(), tokenString)
+			if err == service.ErrAccountDeactivated {
+				http.Error(w, "Account is deactivated", http.StatusUnauthorized)
+				return
+			}
+			if err == service.ErrTokenRevoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
 			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
+			if !claims.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				http.Error(w, "Forbidden: token lacks the "+scope+" scope", http.StatusForbidden)
+				return
+			}
 
-			// Add user ID to context
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			ctx = service.WithOrgID(ctx, claims.OrgID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticateAPIKey serves the request as the key's owner in the key's
+// organization. Permission checks honor the key's restrictions via the context.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKeySvc service.APIKeyService, apiKey string) {
+	key, err := apiKeySvc.Authenticate(r.Context(), apiKey)
+	if err == service.ErrAccountDeactivated {
+		http.Error(w, "Account is deactivated", http.StatusUnauthorized)
+		return
+	}
+	if err == service.ErrInvalidAPIKey {
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to authenticate API key", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), UserIDKey, key.UserID)
+	ctx = service.WithAPIKey(ctx, key)
+	ctx = service.WithOrgID(ctx, key.OrgID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // RBACMiddleware checks if the user has the required permission
 // This is a "factory" that returns a middleware
 func RBACMiddleware(rbacSvc service.RBACService, permission string) mux.MiddlewareFunc {
@@ -59,18 +153,117 @@ func RBACMiddleware(rbacSvc service.RBACService, permission string) mux.Middlewa
 				return
 			}
 
-			allowed, err := rbacSvc.CheckPermission(r.Context(), userID, permission)
+			decision, err := rbacSvc.CheckPermission(withEnvironment(r), userID, permission)
+			if err == service.ErrNoActiveOrg {
+				http.Error(w, "Forbidden: no active organization", http.StatusForbidden)
+				return
+			}
 			if err != nil {
 				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
 				return
 			}
 
-			if !allowed {
-				http.Error(w, "Forbidden: You do not have the required permission", http.StatusForbidden)
+			if !decision.Allowed {
+				respondForbidden(w, decision)
 				return
 			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// PlatformRBACMiddleware checks a permission granted outside of any
+// organization (see RBACService.CheckPlatformPermission). Use it for routes that
+// change state shared by every tenant, where an organization admin must not pass.
+func PlatformRBACMiddleware(rbacSvc service.RBACService, permission string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(int64)
+			if !ok {
+				// This should not happen if AuthMiddleware is applied first
+				http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+				return
+			}
+
+			decision, err := rbacSvc.CheckPlatformPermission(withEnvironment(r), userID, permission)
+			if err != nil {
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+
+			if !decision.Allowed {
+				respondForbidden(w, decision)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ResourceRBACMiddleware checks if the user has the required permission on the
+// resource identified by the {id} route variable, honoring "own" and "team" scopes
+func ResourceRBACMiddleware(rbacSvc service.RBACService, permission, resourceType string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(int64)
+			if !ok {
+				// This should not happen if AuthMiddleware is applied first
+				http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+				return
+			}
+
+			resourceID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid resource ID", http.StatusBadRequest)
+				return
+			}
+
+			decision, err := rbacSvc.CheckResourcePermission(withEnvironment(r), userID, permission, resourceType, resourceID)
+			if err == repository.ErrNotFound {
+				http.Error(w, "Resource not found", http.StatusNotFound)
+				return
+			}
+			if err == service.ErrNoActiveOrg {
+				http.Error(w, "Forbidden: no active organization", http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+
+			if !decision.Allowed {
+				respondForbidden(w, decision)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// respondForbidden writes a 403, naming the deny rule or account policy when one caused it
+func respondForbidden(w http.ResponseWriter, decision domain.Decision) {
+	if decision.Rule != nil || decision.Blocked != "" {
+		http.Error(w, "Forbidden: "+decision.Reason(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, "Forbidden: You do not have the required permission", http.StatusForbidden)
+}
+
+// withEnvironment attaches the request attributes that grant conditions can
+// reference as env.* (client IP, method, path and time)
+func withEnvironment(r *http.Request) context.Context {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	return policy.WithEnvironment(r.Context(), policy.Environment{
+		IP:     ip,
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Time:   time.Now(),
+	})
+}
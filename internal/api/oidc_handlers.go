@@ -0,0 +1,296 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"rbac/internal/domain"
+	"rbac/internal/service"
+	"rbac/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// loginPage is the sign-in form shown by /authorize. The authorization request
+// travels through the form in hidden fields, along with the anti-forgery token.
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<h1>Sign in</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="POST" action="/authorize">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+  <input type="hidden" name="response_type" value="{{.Req.ResponseType}}">
+  <input type="hidden" name="client_id" value="{{.Req.ClientID}}">
+  <input type="hidden" name="redirect_uri" value="{{.Req.RedirectURI}}">
+  <input type="hidden" name="scope" value="{{.Req.Scope}}">
+  <input type="hidden" name="state" value="{{.Req.State}}">
+  <input type="hidden" name="nonce" value="{{.Req.Nonce}}">
+  <input type="hidden" name="code_challenge" value="{{.Req.CodeChallenge}}">
+  <input type="hidden" name="code_challenge_method" value="{{.Req.CodeChallengeMethod}}">
+  {{if .Req.OrgID}}<input type="hidden" name="org_id" value="{{.Req.OrgID}}">{{end}}
+  <label>Username <input name="username" autocomplete="username" required></label>
+  <label>Password <input name="password" type="password" autocomplete="current-password" required></label>
+  <label>Verification code, if two-factor authentication is on <input name="otp" autocomplete="one-time-code"></label>
+  <button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+// csrfCookie holds the anti-forgery token the sign-in form must send back.
+// Another site can make the browser post the form, but cannot read the cookie
+// to fill in the matching field.
+const csrfCookie = "authorize_csrf"
+
+// OpenIDConfigurationHandler serves the OIDC discovery document
+func (h *APIHandler) OpenIDConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	respondWithJSON(w, http.StatusOK, h.oidcSvc.Discovery())
+}
+
+// AuthorizeHandler starts the authorization code flow: GET shows the sign-in
+// form, POST checks the credentials and redirects back to the client with a code
+func (h *APIHandler) AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req := domain.AuthorizeRequest{
+		ResponseType:        r.Form.Get("response_type"),
+		ClientID:            r.Form.Get("client_id"),
+		RedirectURI:         r.Form.Get("redirect_uri"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		Nonce:               r.Form.Get("nonce"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+	}
+	// org_id is our extension: the organization the client wants a session in
+	if orgID := r.Form.Get("org_id"); orgID != "" {
+		id, err := strconv.ParseInt(orgID, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid org_id", http.StatusBadRequest)
+			return
+		}
+		req.OrgID = id
+	}
+
+	if r.Method == http.MethodGet {
+		if err := h.oidcSvc.ValidateAuthorizeRequest(r.Context(), req); err != nil {
+			h.authorizeError(w, r, req, err)
+			return
+		}
+		h.renderLoginPage(w, req, http.StatusOK, "")
+		return
+	}
+
+	if !validCSRFToken(r) {
+		h.renderLoginPage(w, req, http.StatusForbidden, "The sign-in form expired, please try again")
+		return
+	}
+	code, err := h.oidcSvc.Authorize(withEnvironment(r), req, r.PostForm.Get("username"), r.PostForm.Get("password"), r.PostForm.Get("otp"))
+	var throttled *service.LoginThrottledError
+	if err == service.ErrInvalidCredentials || err == service.ErrAccountDeactivated || err == service.ErrInvalidMFACode || errors.As(err, &throttled) {
+		h.renderLoginPage(w, req, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		h.authorizeError(w, r, req, err)
+		return
+	}
+
+	redirectToClient(w, r, req, url.Values{"code": {code}})
+}
+
+// authorizeError reports an authorization failure. Errors about the client or
+// redirect URI are shown here, since redirecting to an unverified URI would
+// make this an open redirector; protocol errors go back to the client.
+func (h *APIHandler) authorizeError(w http.ResponseWriter, r *http.Request, req domain.AuthorizeRequest, err error) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		http.Error(w, validationErr.Message, http.StatusBadRequest)
+		return
+	}
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		redirectToClient(w, r, req, url.Values{"error": {oauthErr.Code}, "error_description": {oauthErr.Description}})
+		return
+	}
+	redirectToClient(w, r, req, url.Values{"error": {"server_error"}})
+}
+
+// redirectToClient redirects to the (already validated) redirect URI with params and the state
+func redirectToClient(w http.ResponseWriter, r *http.Request, req domain.AuthorizeRequest, params url.Values) {
+	target, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		http.Error(w, "Invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	query := target.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// renderLoginPage shows the sign-in form with a fresh anti-forgery token
+func (h *APIHandler) renderLoginPage(w http.ResponseWriter, req domain.AuthorizeRequest, status int, message string) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		http.Error(w, "Failed to render sign-in form", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/authorize",
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.oidcSvc.Discovery().Issuer, "https://"),
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Credentials must not be typed into a frame controlled by another site
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	err = loginPage.Execute(w, struct {
+		Req       domain.AuthorizeRequest
+		CSRFToken string
+		Error     string
+	}{req, token, message})
+	if err != nil {
+		// The status line is already sent, so the page just ends early
+		log.Printf("failed to render sign-in form: %v", err)
+	}
+}
+
+// validCSRFToken checks that the posted form echoes the token in its cookie
+func validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get("csrf_token"))) == 1
+}
+
+// TokenHandler exchanges an authorization code for tokens (RFC 6749 section 4.1.3),
+// or service account credentials for an access token (client_credentials, section 4.4).
+// Clients authenticate with HTTP Basic or client_secret in the form; public clients send only client_id.
+func (h *APIHandler) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+	req := domain.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		// Basic credentials are form-encoded (RFC 6749 section 2.3.1)
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	var resp *domain.TokenResponse
+	var err error
+	if req.GrantType == "client_credentials" {
+		resp, err = h.saSvc.IssueToken(r.Context(), req.ClientID, req.ClientSecret)
+	} else {
+		resp, err = h.oidcSvc.Exchange(r.Context(), req)
+	}
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		status := http.StatusBadRequest
+		if oauthErr.Code == "invalid_client" {
+			status = http.StatusUnauthorized
+		}
+		respondWithOAuthError(w, status, oauthErr.Code, oauthErr.Description)
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "failed to issue tokens")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, errorCode, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, map[string]string{"error": errorCode, "error_description": description})
+}
+
+// UserInfoHandler returns claims about the holder of a client access token
+func (h *APIHandler) UserInfoHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(ClaimsKey).(*utils.Claims)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Claims not found in context")
+		return
+	}
+
+	info, err := h.oidcSvc.UserInfo(r.Context(), claims)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to load user info")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, info)
+}
+
+// --- OAuth client administration ---
+
+// CreateOAuthClientHandler registers an OIDC client. The secret is only returned here.
+func (h *APIHandler) CreateOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	resp, err := h.oidcSvc.CreateClient(r.Context(), req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to create client")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// ListOAuthClientsHandler lists registered OIDC clients
+func (h *APIHandler) ListOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.oidcSvc.ListClients(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list clients")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, clients)
+}
+
+// DeleteOAuthClientHandler removes an OIDC client and its outstanding codes
+func (h *APIHandler) DeleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.oidcSvc.DeleteClient(r.Context(), mux.Vars(r)["clientID"]); err != nil {
+		respondWithServiceError(w, err, "Failed to delete client")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+	"strconv"
+)
+
+// GetProductHandler returns one product of the caller's active organization
+func (h *APIHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	product, err := h.productSvc.GetProduct(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to load product")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, product)
+}
+
+// ListProductsHandler lists products of the caller's active organization.
+// Query parameters: name, min_price, max_price, created_by, cursor and limit.
+func (h *APIHandler) ListProductsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.ProductFilter{
+		Name:   query.Get("name"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if v := query.Get("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid min_price")
+			return
+		}
+		filter.MinPrice = &price
+	}
+	if v := query.Get("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid max_price")
+			return
+		}
+		filter.MaxPrice = &price
+	}
+	if v := query.Get("created_by"); v != "" {
+		userID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid created_by")
+			return
+		}
+		filter.CreatedBy = &userID
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := h.productSvc.ListProducts(r.Context(), filter)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list products")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// UpdateProductHandler replaces a product's name and price
+func (h *APIHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var req domain.UpdateProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	product, err := h.productSvc.UpdateProduct(r.Context(), id, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to update product")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, product)
+}
+
+// DeleteProductHandler deletes a product
+func (h *APIHandler) DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	if err := h.productSvc.DeleteProduct(r.Context(), id); err != nil {
+		respondWithServiceError(w, err, "Failed to delete product")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- Role Handlers ---
+
+// ListRolesHandler lists every role
+func (h *APIHandler) ListRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleSvc.ListRoles(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list roles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, roles)
+}
+
+// GetRoleHandler returns one role
+func (h *APIHandler) GetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	role, err := h.roleSvc.GetRole(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to load role")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, role)
+}
+
+// CreateRoleHandler creates a role, optionally inheriting from a parent role
+func (h *APIHandler) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	role, err := h.roleSvc.CreateRole(r.Context(), req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to create role")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, role)
+}
+
+// UpdateRoleHandler renames a role and sets its parent
+func (h *APIHandler) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	var req domain.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	role, err := h.roleSvc.UpdateRole(r.Context(), id, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to update role")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, role)
+}
+
+// DeleteRoleHandler deletes a role and every assignment of it
+func (h *APIHandler) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	if err := h.roleSvc.DeleteRole(r.Context(), id); err != nil {
+		respondWithServiceError(w, err, "Failed to delete role")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRoleGrantsHandler lists the permissions attached to a role (not inherited ones)
+func (h *APIHandler) ListRoleGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	grants, err := h.roleSvc.ListRoleGrants(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list role permissions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, grants)
+}
+
+// GrantPermissionHandler attaches a permission to a role
+func (h *APIHandler) GrantPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	var req domain.GrantPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	grant, err := h.roleSvc.GrantPermission(r.Context(), id, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to grant permission")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, grant)
+}
+
+// RevokePermissionHandler detaches a permission from a role
+func (h *APIHandler) RevokePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+	permissionID, err := parseIDVar(r, "permissionID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid permission ID")
+		return
+	}
+
+	if err := h.roleSvc.RevokePermission(r.Context(), id, permissionID); err != nil {
+		respondWithServiceError(w, err, "Failed to revoke permission")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Permission Handlers ---
+
+// ListPermissionsHandler lists every permission
+func (h *APIHandler) ListPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	perms, err := h.roleSvc.ListPermissions(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list permissions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, perms)
+}
+
+// GetPermissionHandler returns one permission
+func (h *APIHandler) GetPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid permission ID")
+		return
+	}
+
+	perm, err := h.roleSvc.GetPermission(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to load permission")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, perm)
+}
+
+// CreatePermissionHandler creates a permission
+func (h *APIHandler) CreatePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.PermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	perm, err := h.roleSvc.CreatePermission(r.Context(), req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to create permission")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, perm)
+}
+
+// UpdatePermissionHandler renames or re-describes a permission
+func (h *APIHandler) UpdatePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid permission ID")
+		return
+	}
+
+	var req domain.PermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	perm, err := h.roleSvc.UpdatePermission(r.Context(), id, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to update permission")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, perm)
+}
+
+// DeletePermissionHandler deletes a permission and every grant of it
+func (h *APIHandler) DeletePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid permission ID")
+		return
+	}
+
+	if err := h.roleSvc.DeletePermission(r.Context(), id); err != nil {
+		respondWithServiceError(w, err, "Failed to delete permission")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,25 +3,82 @@ package api
 import (
 	"log"
 	"net/http"
+	"rbac/internal/service"
 
 	"github.com/gorilla/mux"
 )
 
 // RegisterRoutes sets up all routes for the application
-func (h *APIHandler) RegisterRoutes(router *mux.Router, jwtSecret string) {
+func (h *APIHandler) RegisterRoutes(router *mux.Router) {
 	// Create middleware instances
-	auth := AuthMiddleware(jwtSecret)
+	auth := AuthMiddleware(h.authSvc, h.apiKeySvc)
+	// Tokens issued to OIDC clients are only good for the OIDC endpoints
+	clientAuth := ClientTokenMiddleware(h.authSvc, "openid")
 	// Create RBAC middleware for specific permissions
 	canCreateProduct := RBACMiddleware(h.rbacSvc, "create_product")
-	canReadProduct := RBACMiddleware(h.rbacSvc, "read_product")
+	// Resource-aware variant: honors "own"/"team" scoped grants on the {id} product
+	canReadProduct := ResourceRBACMiddleware(h.rbacSvc, "read_product", service.ResourceProduct)
+	canListProducts := RBACMiddleware(h.rbacSvc, "list_products")
+	canUpdateProduct := ResourceRBACMiddleware(h.rbacSvc, "update_product", service.ResourceProduct)
+	canDeleteProduct := ResourceRBACMiddleware(h.rbacSvc, "delete_product", service.ResourceProduct)
+	// Roles and permissions are shared by every organization, so managing them
+	// takes a platform grant rather than a role in the active organization
+	canManageRoles := PlatformRBACMiddleware(h.rbacSvc, "manage_roles")
+	canManageGroups := RBACMiddleware(h.rbacSvc, "manage_groups")
+	canManageUsers := RBACMiddleware(h.rbacSvc, "manage_users")
+	// Accounts and sessions are shared by every organization the user belongs to
+	canManageAccounts := PlatformRBACMiddleware(h.rbacSvc, "manage_accounts")
+	canExplainAuthz := RBACMiddleware(h.rbacSvc, "explain_authz")
+	// OAuth clients can sign in users of any organization
+	canManageOAuthClients := PlatformRBACMiddleware(h.rbacSvc, "manage_oauth_clients")
+	canManageServiceAccounts := RBACMiddleware(h.rbacSvc, "manage_service_accounts")
 	// canDeleteUser := RBACMiddleware(h.rbacSvc, "delete_user") // Example
 
 	// Public routes (Auth)
 	router.HandleFunc("/register", h.RegisterHandler).Methods("POST")
 	router.HandleFunc("/login", h.LoginHandler).Methods("POST")
+	router.HandleFunc("/login/mfa", h.MFALoginHandler).Methods("POST")
+	router.HandleFunc("/token/refresh", h.RefreshTokenHandler).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", h.JWKSHandler).Methods("GET")
+	router.Handle("/logout", auth(http.HandlerFunc(h.LogoutHandler))).Methods("POST")
+
+	// Account recovery and email verification
+	router.HandleFunc("/password/forgot", h.ForgotPasswordHandler).Methods("POST")
+	router.HandleFunc("/password/reset", h.ResetPasswordHandler).Methods("POST")
+	router.Handle("/password/change", auth(http.HandlerFunc(h.ChangePasswordHandler))).Methods("POST")
+	router.Handle("/email/verification", auth(http.HandlerFunc(h.SendVerificationEmailHandler))).Methods("POST")
+	router.HandleFunc("/email/verify", h.VerifyEmailHandler).Methods("GET", "POST")
+
+	// OpenID Connect provider (authorization code flow with PKCE)
+	router.HandleFunc("/.well-known/openid-configuration", h.OpenIDConfigurationHandler).Methods("GET")
+	router.HandleFunc("/authorize", h.AuthorizeHandler).Methods("GET", "POST")
+	router.HandleFunc("/token", h.TokenHandler).Methods("POST")
+	router.Handle("/userinfo", clientAuth(http.HandlerFunc(h.UserInfoHandler))).Methods("GET", "POST")
+
+	// Multi-factor authentication (any logged-in user, for their own account)
+	mfaRouter := router.PathPrefix("/mfa").Subrouter()
+	mfaRouter.Use(auth)
+	mfaRouter.HandleFunc("", h.MFAStatusHandler).Methods("GET")
+	mfaRouter.HandleFunc("/totp", h.BeginTOTPEnrollmentHandler).Methods("POST")
+	mfaRouter.HandleFunc("/totp/verify", h.ConfirmTOTPEnrollmentHandler).Methods("POST")
+	mfaRouter.HandleFunc("/totp", h.DisableTOTPHandler).Methods("DELETE")
+	mfaRouter.HandleFunc("/recovery-codes", h.RegenerateRecoveryCodesHandler).Methods("POST")
+
+	// Personal API keys (any logged-in user, for their own keys)
+	apiKeyRouter := router.PathPrefix("/api-keys").Subrouter()
+	apiKeyRouter.Use(auth)
+	apiKeyRouter.HandleFunc("", h.CreateAPIKeyHandler).Methods("POST")
+	apiKeyRouter.HandleFunc("", h.ListAPIKeysHandler).Methods("GET")
+	apiKeyRouter.HandleFunc("/{id:[0-9]+}", h.DeleteAPIKeyHandler).Methods("DELETE")
 
 	router.HandleFunc("/test-graphql/{code}", h.GetCountryHandler).Methods("GET")
 
+	// Organization membership and switching (any logged-in user)
+	orgRouter := router.PathPrefix("/orgs").Subrouter()
+	orgRouter.Use(auth)
+	orgRouter.HandleFunc("", h.ListOrganizationsHandler).Methods("GET")
+	orgRouter.HandleFunc("/switch", h.SwitchOrganizationHandler).Methods("POST")
+
 	// Protected routes (Products)
 	// We apply middleware in order: Auth (to get user) -> RBAC (to check perm)
 	productRouter := router.PathPrefix("/products").Subrouter()
@@ -32,11 +89,108 @@ func (h *APIHandler) RegisterRoutes(router *mux.Router, jwtSecret string) {
 		canCreateProduct(http.HandlerFunc(h.CreateProductHandler)),
 	)
 	
+	// GET /products - Requires 'list_products' permission
+	productRouter.Handle("", canListProducts(http.HandlerFunc(h.ListProductsHandler))).Methods("GET")
+
 	// GET /products/{id} - Requires 'read_product' permission
 	productRouter.HandleFunc("/{id:[0-9]+}", h.GetProductHandler).Methods("GET").Handler(
 		canReadProduct(http.HandlerFunc(h.GetProductHandler)),
 	)
-	
+
+	// PUT /products/{id} - Requires 'update_product' permission
+	productRouter.Handle("/{id:[0-9]+}", canUpdateProduct(http.HandlerFunc(h.UpdateProductHandler))).Methods("PUT")
+
+	// DELETE /products/{id} - Requires 'delete_product' permission
+	productRouter.Handle("/{id:[0-9]+}", canDeleteProduct(http.HandlerFunc(h.DeleteProductHandler))).Methods("DELETE")
+
+	// Authorization introspection
+	authzRouter := router.PathPrefix("/authz").Subrouter()
+	authzRouter.Use(auth)
+
+	// POST /authz/check - which of these permissions does the caller hold
+	authzRouter.HandleFunc("/check", h.CheckPermissionsHandler).Methods("POST")
+	// POST /authz/explain - admin-only decision derivation for any user
+	authzRouter.Handle("/explain", canExplainAuthz(http.HandlerFunc(h.ExplainHandler))).Methods("POST")
+
+	// Admin routes, each guarded by its own permission
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(auth)
+
+	roleRouter := adminRouter.PathPrefix("/roles").Subrouter()
+	roleRouter.Use(canManageRoles)
+	roleRouter.HandleFunc("", h.ListRolesHandler).Methods("GET")
+	roleRouter.HandleFunc("", h.CreateRoleHandler).Methods("POST")
+	roleRouter.HandleFunc("/{id:[0-9]+}", h.GetRoleHandler).Methods("GET")
+	roleRouter.HandleFunc("/{id:[0-9]+}", h.UpdateRoleHandler).Methods("PUT")
+	roleRouter.HandleFunc("/{id:[0-9]+}", h.DeleteRoleHandler).Methods("DELETE")
+	roleRouter.HandleFunc("/{id:[0-9]+}/permissions", h.ListRoleGrantsHandler).Methods("GET")
+	roleRouter.HandleFunc("/{id:[0-9]+}/permissions", h.GrantPermissionHandler).Methods("POST")
+	roleRouter.HandleFunc("/{id:[0-9]+}/permissions/{permissionID:[0-9]+}", h.RevokePermissionHandler).Methods("DELETE")
+
+	permissionRouter := adminRouter.PathPrefix("/permissions").Subrouter()
+	permissionRouter.Use(canManageRoles)
+	permissionRouter.HandleFunc("", h.ListPermissionsHandler).Methods("GET")
+	permissionRouter.HandleFunc("", h.CreatePermissionHandler).Methods("POST")
+	permissionRouter.HandleFunc("/{id:[0-9]+}", h.GetPermissionHandler).Methods("GET")
+	permissionRouter.HandleFunc("/{id:[0-9]+}", h.UpdatePermissionHandler).Methods("PUT")
+	permissionRouter.HandleFunc("/{id:[0-9]+}", h.DeletePermissionHandler).Methods("DELETE")
+
+	oauthClientRouter := adminRouter.PathPrefix("/oauth-clients").Subrouter()
+	oauthClientRouter.Use(canManageOAuthClients)
+	oauthClientRouter.HandleFunc("", h.CreateOAuthClientHandler).Methods("POST")
+	oauthClientRouter.HandleFunc("", h.ListOAuthClientsHandler).Methods("GET")
+	oauthClientRouter.HandleFunc("/{clientID:[0-9a-f]{32}}", h.DeleteOAuthClientHandler).Methods("DELETE")
+
+	serviceAccountRouter := adminRouter.PathPrefix("/service-accounts").Subrouter()
+	serviceAccountRouter.Use(canManageServiceAccounts)
+	serviceAccountRouter.HandleFunc("", h.CreateServiceAccountHandler).Methods("POST")
+	serviceAccountRouter.HandleFunc("", h.ListServiceAccountsHandler).Methods("GET")
+	serviceAccountRouter.HandleFunc("/{id:[0-9]+}/rotate-secret", h.RotateServiceAccountSecretHandler).Methods("POST")
+	serviceAccountRouter.HandleFunc("/{id:[0-9]+}", h.DeleteServiceAccountHandler).Methods("DELETE")
+
+	groupRouter := adminRouter.PathPrefix("/groups").Subrouter()
+	groupRouter.Use(canManageGroups)
+	groupRouter.HandleFunc("", h.CreateGroupHandler).Methods("POST")
+	groupRouter.HandleFunc("", h.ListGroupsHandler).Methods("GET")
+	groupRouter.HandleFunc("/{id:[0-9]+}/members", h.AddGroupMemberHandler).Methods("POST")
+	groupRouter.HandleFunc("/{id:[0-9]+}/members/{userID:[0-9]+}", h.RemoveGroupMemberHandler).Methods("DELETE")
+	groupRouter.HandleFunc("/{id:[0-9]+}/roles", h.AssignGroupRoleHandler).Methods("POST")
+	groupRouter.HandleFunc("/{id:[0-9]+}/roles/{roleID:[0-9]+}", h.RevokeGroupRoleHandler).Methods("DELETE")
+
+	// GET /admin/users?q=&page=&page_size= - search members of the active organization
+	adminRouter.Handle("/users", canManageUsers(http.HandlerFunc(h.SearchUsersHandler))).Methods("GET")
+	// GET /admin/users/{id}/effective-roles - roles with where each one came from
+	adminRouter.Handle("/users/{id:[0-9]+}/effective-roles",
+		canManageUsers(http.HandlerFunc(h.GetEffectiveRolesHandler)),
+	).Methods("GET")
+	// POST /admin/users/{id}/roles - assign a role, optionally time-bound
+	adminRouter.Handle("/users/{id:[0-9]+}/roles",
+		canManageUsers(http.HandlerFunc(h.AssignUserRoleHandler)),
+	).Methods("POST")
+	// GET /admin/users/{id}/roles - direct assignments, with their validity windows
+	adminRouter.Handle("/users/{id:[0-9]+}/roles",
+		canManageUsers(http.HandlerFunc(h.ListUserRolesHandler)),
+	).Methods("GET")
+	// DELETE /admin/users/{id}/roles/{roleID} - revoke a direct assignment
+	adminRouter.Handle("/users/{id:[0-9]+}/roles/{roleID:[0-9]+}",
+		canManageUsers(http.HandlerFunc(h.RevokeUserRoleHandler)),
+	).Methods("DELETE")
+	// POST /admin/users/{id}/deactivate and /activate - lock or unlock the account
+	adminRouter.Handle("/users/{id:[0-9]+}/deactivate",
+		canManageAccounts(http.HandlerFunc(h.DeactivateUserHandler)),
+	).Methods("POST")
+	adminRouter.Handle("/users/{id:[0-9]+}/activate",
+		canManageAccounts(http.HandlerFunc(h.ActivateUserHandler)),
+	).Methods("POST")
+	// POST /admin/users/{id}/revoke-sessions - log the user out everywhere
+	adminRouter.Handle("/users/{id:[0-9]+}/revoke-sessions",
+		canManageAccounts(http.HandlerFunc(h.RevokeUserSessionsHandler)),
+	).Methods("POST")
+	// POST /admin/users/{id}/unlock - lift a lockout after failed logins
+	adminRouter.Handle("/users/{id:[0-9]+}/unlock",
+		canManageAccounts(http.HandlerFunc(h.UnlockUserHandler)),
+	).Methods("POST")
+
 	// Example of a route only an admin could access
 	// adminRouter := router.PathPrefix("/admin").Subrouter()
 	// adminRouter.Use(auth, canDeleteUser)
@@ -44,11 +198,3 @@ func (h *APIHandler) RegisterRoutes(router *mux.Router, jwtSecret string) {
 
 	log.Println("Registered API routes")
 }
-
-// Dummy handler to satisfy the routes file
-func (h *APIHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	// In a real app, you'd call h.productSvc.GetProduct(r.Context(), id)
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "GET product " + id, "status": "ok"})
-}
\ No newline at end of file
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rbac/internal/domain"
+)
+
+// --- Service Account Handlers ---
+
+// CreateServiceAccountHandler creates a service account in the caller's active
+// organization. Roles are assigned through /admin/users/{id}/roles like any user's.
+func (h *APIHandler) CreateServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	creds, err := h.saSvc.Create(r.Context(), actorID, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to create service account")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, creds)
+}
+
+// ListServiceAccountsHandler lists the service accounts of the caller's active organization
+func (h *APIHandler) ListServiceAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.saSvc.List(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to list service accounts")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, accounts)
+}
+
+// RotateServiceAccountSecretHandler issues a new secret and revokes tokens issued with the old one
+func (h *APIHandler) RotateServiceAccountSecretHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	creds, err := h.saSvc.RotateSecret(r.Context(), actorID, userID)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to rotate secret")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, creds)
+}
+
+// DeleteServiceAccountHandler deletes a service account
+func (h *APIHandler) DeleteServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseIDVar(r, "id")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	actorID, ok := r.Context().Value(UserIDKey).(int64)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User ID not found in context")
+		return
+	}
+
+	if err := h.saSvc.Delete(r.Context(), actorID, userID); err != nil {
+		respondWithServiceError(w, err, "Failed to delete service account")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
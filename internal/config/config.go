@@ -1,20 +1,67 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// signingKeyEncryptionKeyPlaceholder is the value shipped in .env, which must
+// be replaced before the server will start
+const signingKeyEncryptionKeyPlaceholder = "change-me"
+
 // Config holds all configuration for the application
 type Config struct {
 	ServerPort      string
 	DatabaseURL     string
-	JWTSecret       string
-	JWTExpirationInHours int64
+	TokenSigningAlgorithm string
+	SigningKeyRotationHours int64
+	SigningKeyOverlapHours  int64
+	SigningKeyCheckMinutes  int64
+	SigningKeyEncryptionKey []byte
+	AccessTokenTTLMinutes int64
+	RefreshTokenTTLHours  int64
+	RoleSweepIntervalMinutes   int64
+	RecordSweepIntervalMinutes int64
+	PermissionCacheTTLSeconds int64
+	PermissionCacheSize       int
+	TokenRevocationSyncSeconds int64
+	OIDCIssuer                 string
+	MFAIssuer                  string
+	MFAChallengeTTLMinutes     int64
+	MailTransport              string
+	MailFile                   string
+	MailFrom                   string
+	SMTPHost                   string
+	SMTPPort                   int
+	SMTPUsername               string
+	SMTPPassword               string
+	PasswordResetTTLMinutes    int64
+	EmailVerificationTTLHours  int64
+	UnverifiedEmailBlockedPermissions []string
+	LoginFreeAttempts         int
+	LoginBackoffBaseSeconds   int64
+	LoginBackoffMaxSeconds    int64
+	LoginFailureWindowMinutes int64
+	LoginAccountLockoutThreshold int
+	LoginIPLockoutThreshold      int
+	LoginLockoutMinutes          int64
+	MFALockoutThreshold          int
+	PasswordResetAddressLimit    int
+	PasswordResetIPLimit         int
+	PasswordResetWindowMinutes   int64
+	PasswordMinLength  int
+	PasswordBreachFile string
+	PasswordHashAlgorithm   string
+	BcryptCost              int
+	Argon2MemoryKiB         uint32
+	Argon2Iterations        uint32
+	Argon2Parallelism       uint8
 }
 
 // LoadConfig loads configuration from .env file
@@ -29,7 +76,10 @@ func LoadConfig() (*Config, error) {
 	dbPort := os.Getenv("DB_PORT")
 	dbName := os.Getenv("DB_NAME")
 
-	databaseURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+	// The driver reads and writes TIMESTAMPs as UTC (loc), so the session must
+	// use UTC as well; otherwise CURRENT_TIMESTAMP and the times the services
+	// compare it with are off by the server's offset
+	databaseURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
 		dbUser, dbPass, dbHost, dbPort, dbName,
 	)
 
@@ -38,20 +88,277 @@ func LoadConfig() (*Config, error) {
 		serverPort = ":8080"
 	}
     
-	jwtSecret := os.Getenv("JWT_SECRET_KEY")
-	if jwtSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET_KEY is not set")
+	// Access tokens are short-lived; sessions are extended with refresh tokens
+	accessTTL, err := strconv.ParseInt(os.Getenv("ACCESS_TOKEN_TTL_MINUTES"), 10, 64)
+	if err != nil || accessTTL <= 0 {
+		accessTTL = 15
+	}
+	refreshTTL, err := strconv.ParseInt(os.Getenv("REFRESH_TOKEN_TTL_HOURS"), 10, 64)
+	if err != nil || refreshTTL <= 0 {
+		refreshTTL = 720
+	}
+
+	roleSweepMinutes, err := strconv.ParseInt(os.Getenv("ROLE_SWEEP_INTERVAL_MINUTES"), 10, 64)
+	if err != nil || roleSweepMinutes <= 0 {
+		roleSweepMinutes = 5
+	}
+	recordSweepMinutes, err := strconv.ParseInt(os.Getenv("RECORD_SWEEP_INTERVAL_MINUTES"), 10, 64)
+	if err != nil || recordSweepMinutes <= 0 {
+		recordSweepMinutes = 60
 	}
 
-	jwtExpHours, err := strconv.ParseInt(os.Getenv("JWT_EXPIRATION_HOURS"), 10, 64)
+	// A TTL or size of 0 disables the permission cache
+	cacheTTL, err := strconv.ParseInt(os.Getenv("PERMISSION_CACHE_TTL_SECONDS"), 10, 64)
+	if err != nil {
+		cacheTTL = 60
+	}
+	cacheSize, err := strconv.Atoi(os.Getenv("PERMISSION_CACHE_SIZE"))
 	if err != nil {
-		jwtExpHours = 72
+		cacheSize = 10000
+	}
+
+	signingAlg := os.Getenv("TOKEN_SIGNING_ALGORITHM")
+	if signingAlg == "" {
+		signingAlg = "ES256"
+	}
+	if signingAlg != "ES256" && signingAlg != "RS256" {
+		return nil, fmt.Errorf("TOKEN_SIGNING_ALGORITHM must be ES256 or RS256, got %q", signingAlg)
+	}
+	keyRotationHours, err := strconv.ParseInt(os.Getenv("SIGNING_KEY_ROTATION_HOURS"), 10, 64)
+	if err != nil || keyRotationHours <= 0 {
+		keyRotationHours = 720
+	}
+	// A retired key must outlive every token it signed (checked below, once
+	// every token lifetime is known)
+	keyOverlapHours, err := strconv.ParseInt(os.Getenv("SIGNING_KEY_OVERLAP_HOURS"), 10, 64)
+	if err != nil || keyOverlapHours <= 0 {
+		keyOverlapHours = 72
+	}
+	// How often keys are reloaded and rotated; a rotation can be this late
+	keyCheckMinutes, err := strconv.ParseInt(os.Getenv("SIGNING_KEY_CHECK_MINUTES"), 10, 64)
+	if err != nil || keyCheckMinutes <= 0 {
+		keyCheckMinutes = 5
+	}
+	// Base64 AES-256 key that encrypts the private signing keys in the database
+	// (e.g. from `openssl rand -base64 32`), so a copy of the database or a
+	// backup is not enough to forge tokens
+	encodedKeyEncryptionKey := os.Getenv("SIGNING_KEY_ENCRYPTION_KEY")
+	if encodedKeyEncryptionKey == signingKeyEncryptionKeyPlaceholder {
+		return nil, fmt.Errorf("SIGNING_KEY_ENCRYPTION_KEY is still the placeholder from .env; set your own key")
+	}
+	keyEncryptionKey, err := base64.StdEncoding.DecodeString(encodedKeyEncryptionKey)
+	if err != nil || len(keyEncryptionKey) != 32 {
+		return nil, fmt.Errorf("SIGNING_KEY_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	}
+
+	// How often other instances' logouts are picked up
+	revocationSync, err := strconv.ParseInt(os.Getenv("TOKEN_REVOCATION_SYNC_SECONDS"), 10, 64)
+	if err != nil || revocationSync <= 0 {
+		revocationSync = 30
+	}
+
+	// External base URL; it is the iss of ID tokens and prefixes the discovery endpoints
+	oidcIssuer := os.Getenv("OIDC_ISSUER")
+	if oidcIssuer == "" {
+		oidcIssuer = "http://localhost" + serverPort
+	}
+
+	// Label shown next to the account in authenticator apps
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = "go-rbac-api"
+	}
+	// Time allowed between the password and code steps of login
+	mfaChallengeTTL, err := strconv.ParseInt(os.Getenv("MFA_CHALLENGE_TTL_MINUTES"), 10, 64)
+	if err != nil || mfaChallengeTTL <= 0 {
+		mfaChallengeTTL = 5
+	}
+
+	// How account emails are delivered: written to the log, appended to
+	// MAIL_FILE, or sent through an SMTP relay
+	mailTransport := os.Getenv("MAIL_TRANSPORT")
+	if mailTransport == "" {
+		mailTransport = "log"
+	}
+	if mailTransport != "log" && mailTransport != "file" && mailTransport != "smtp" {
+		return nil, fmt.Errorf("MAIL_TRANSPORT must be log, file or smtp, got %q", mailTransport)
+	}
+	mailFile := os.Getenv("MAIL_FILE")
+	if mailFile == "" {
+		mailFile = "mail.log"
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "no-reply@localhost"
+	}
+	smtpHost := os.Getenv("SMTP_HOST")
+	if mailTransport == "smtp" && smtpHost == "" {
+		return nil, fmt.Errorf("SMTP_HOST is required when MAIL_TRANSPORT is smtp")
+	}
+	smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil || smtpPort <= 0 {
+		smtpPort = 587
+	}
+
+	passwordResetTTL, err := strconv.ParseInt(os.Getenv("PASSWORD_RESET_TTL_MINUTES"), 10, 64)
+	if err != nil || passwordResetTTL <= 0 {
+		passwordResetTTL = 30
+	}
+	emailVerificationTTL, err := strconv.ParseInt(os.Getenv("EMAIL_VERIFICATION_TTL_HOURS"), 10, 64)
+	if err != nil || emailVerificationTTL <= 0 {
+		emailVerificationTTL = 48
+	}
+
+	// Access tokens, ID tokens and account email tokens are all signed with the
+	// rotating keys. The key that signed a token may be rotated out up to a check
+	// interval late, and must keep verifying until the token expires.
+	longestTokenMinutes := accessTTL
+	if passwordResetTTL > longestTokenMinutes {
+		longestTokenMinutes = passwordResetTTL
+	}
+	if emailVerificationTTL*60 > longestTokenMinutes {
+		longestTokenMinutes = emailVerificationTTL * 60
+	}
+	if keyOverlapHours*60 < longestTokenMinutes+keyCheckMinutes {
+		return nil, fmt.Errorf("SIGNING_KEY_OVERLAP_HOURS must cover the longest token lifetime (%d minutes) plus SIGNING_KEY_CHECK_MINUTES", longestTokenMinutes)
+	}
+
+	// Comma-separated permission patterns (wildcards allowed) refused until the
+	// user verifies their email address
+	var unverifiedBlocked []string
+	for _, p := range strings.Split(os.Getenv("UNVERIFIED_EMAIL_BLOCKED_PERMISSIONS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			unverifiedBlocked = append(unverifiedBlocked, p)
+		}
+	}
+
+	// Failed logins: after LOGIN_FREE_ATTEMPTS, each attempt waits twice as long
+	// as the last (up to the max); at a threshold the account or IP is locked
+	// out for LOGIN_LOCKOUT_MINUTES. A threshold of 0 disables that lockout.
+	loginFreeAttempts, err := strconv.Atoi(os.Getenv("LOGIN_FREE_ATTEMPTS"))
+	if err != nil || loginFreeAttempts < 0 {
+		loginFreeAttempts = 3
+	}
+	loginBackoffBase, err := strconv.ParseInt(os.Getenv("LOGIN_BACKOFF_BASE_SECONDS"), 10, 64)
+	if err != nil || loginBackoffBase < 0 {
+		loginBackoffBase = 1
+	}
+	loginBackoffMax, err := strconv.ParseInt(os.Getenv("LOGIN_BACKOFF_MAX_SECONDS"), 10, 64)
+	if err != nil || loginBackoffMax <= 0 {
+		loginBackoffMax = 60
+	}
+	loginFailureWindow, err := strconv.ParseInt(os.Getenv("LOGIN_FAILURE_WINDOW_MINUTES"), 10, 64)
+	if err != nil || loginFailureWindow <= 0 {
+		loginFailureWindow = 15
+	}
+	accountLockoutThreshold, err := strconv.Atoi(os.Getenv("LOGIN_ACCOUNT_LOCKOUT_THRESHOLD"))
+	if err != nil || accountLockoutThreshold < 0 {
+		accountLockoutThreshold = 10
+	}
+	// Higher than per account: many users may share an address behind NAT
+	ipLockoutThreshold, err := strconv.Atoi(os.Getenv("LOGIN_IP_LOCKOUT_THRESHOLD"))
+	if err != nil || ipLockoutThreshold < 0 {
+		ipLockoutThreshold = 50
+	}
+	loginLockout, err := strconv.ParseInt(os.Getenv("LOGIN_LOCKOUT_MINUTES"), 10, 64)
+	if err != nil || loginLockout <= 0 {
+		loginLockout = 15
+	}
+	mfaLockoutThreshold, err := strconv.Atoi(os.Getenv("MFA_LOCKOUT_THRESHOLD"))
+	if err != nil || mfaLockoutThreshold < 0 {
+		mfaLockoutThreshold = 10
+	}
+	resetAddressLimit, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_ADDRESS_LIMIT"))
+	if err != nil || resetAddressLimit < 0 {
+		resetAddressLimit = 3
+	}
+	resetIPLimit, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_IP_LIMIT"))
+	if err != nil || resetIPLimit < 0 {
+		resetIPLimit = 20
+	}
+	resetWindow, err := strconv.ParseInt(os.Getenv("PASSWORD_RESET_WINDOW_MINUTES"), 10, 64)
+	if err != nil || resetWindow <= 0 {
+		resetWindow = 60
+	}
+
+	passwordMinLength, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH"))
+	if err != nil || passwordMinLength <= 0 {
+		passwordMinLength = 12
+	}
+	// Optional sorted "SHA1:COUNT" corpus of breached passwords; empty skips the check
+	passwordBreachFile := os.Getenv("PASSWORD_BREACH_FILE")
+
+	// New password hashes use this algorithm and cost; weaker stored hashes are
+	// upgraded as their users log in
+	passwordHashAlg := os.Getenv("PASSWORD_HASH_ALGORITHM")
+	if passwordHashAlg == "" {
+		passwordHashAlg = "argon2id"
+	}
+	if passwordHashAlg != "argon2id" && passwordHashAlg != "bcrypt" {
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", passwordHashAlg)
+	}
+	bcryptCost, err := strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	if err != nil || bcryptCost < 10 || bcryptCost > 31 {
+		bcryptCost = 14
+	}
+	argon2Memory, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY_KIB"), 10, 32)
+	if err != nil || argon2Memory < 8*1024 {
+		argon2Memory = 64 * 1024
+	}
+	argon2Iterations, err := strconv.ParseUint(os.Getenv("ARGON2_ITERATIONS"), 10, 32)
+	if err != nil || argon2Iterations == 0 {
+		argon2Iterations = 3
+	}
+	argon2Parallelism, err := strconv.ParseUint(os.Getenv("ARGON2_PARALLELISM"), 10, 8)
+	if err != nil || argon2Parallelism == 0 {
+		argon2Parallelism = 2
 	}
 
 	return &Config{
 		ServerPort:      serverPort,
 		DatabaseURL:     databaseURL,
-		JWTSecret:       jwtSecret,
-		JWTExpirationInHours: jwtExpHours,
+		TokenSigningAlgorithm: signingAlg,
+		SigningKeyRotationHours: keyRotationHours,
+		SigningKeyOverlapHours:  keyOverlapHours,
+		SigningKeyCheckMinutes:  keyCheckMinutes,
+		SigningKeyEncryptionKey: keyEncryptionKey,
+		AccessTokenTTLMinutes: accessTTL,
+		RefreshTokenTTLHours:  refreshTTL,
+		RoleSweepIntervalMinutes:   roleSweepMinutes,
+		RecordSweepIntervalMinutes: recordSweepMinutes,
+		PermissionCacheTTLSeconds: cacheTTL,
+		PermissionCacheSize:       cacheSize,
+		TokenRevocationSyncSeconds: revocationSync,
+		OIDCIssuer:                 oidcIssuer,
+		MFAIssuer:                  mfaIssuer,
+		MFAChallengeTTLMinutes:     mfaChallengeTTL,
+		MailTransport:              mailTransport,
+		MailFile:                   mailFile,
+		MailFrom:                   mailFrom,
+		SMTPHost:                   smtpHost,
+		SMTPPort:                   smtpPort,
+		SMTPUsername:               os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:               os.Getenv("SMTP_PASSWORD"),
+		PasswordResetTTLMinutes:    passwordResetTTL,
+		EmailVerificationTTLHours:  emailVerificationTTL,
+		UnverifiedEmailBlockedPermissions: unverifiedBlocked,
+		LoginFreeAttempts:         loginFreeAttempts,
+		LoginBackoffBaseSeconds:   loginBackoffBase,
+		LoginBackoffMaxSeconds:    loginBackoffMax,
+		LoginFailureWindowMinutes: loginFailureWindow,
+		LoginAccountLockoutThreshold: accountLockoutThreshold,
+		LoginIPLockoutThreshold:      ipLockoutThreshold,
+		LoginLockoutMinutes:          loginLockout,
+		MFALockoutThreshold:          mfaLockoutThreshold,
+		PasswordResetAddressLimit:    resetAddressLimit,
+		PasswordResetIPLimit:         resetIPLimit,
+		PasswordResetWindowMinutes:   resetWindow,
+		PasswordMinLength:  passwordMinLength,
+		PasswordBreachFile: passwordBreachFile,
+		PasswordHashAlgorithm:   passwordHashAlg,
+		BcryptCost:              bcryptCost,
+		Argon2MemoryKiB:         uint32(argon2Memory),
+		Argon2Iterations:        uint32(argon2Iterations),
+		Argon2Parallelism:       uint8(argon2Parallelism),
 	}, nil
 }
\ No newline at end of file
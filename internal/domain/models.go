@@ -1,31 +1,403 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // User represents a user in the system
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // Don't expose this
-	CreatedAt    time.Time `json:"created_at"`
+	ID                int64      `json:"id"`
+	Username          string     `json:"username"`
+	Email             string     `json:"email,omitempty"` // Empty for service accounts
+	EmailVerifiedAt   *time.Time `json:"email_verified_at,omitempty"`
+	PasswordHash      string     `json:"-"`               // Don't expose this
+	TeamID            *int64     `json:"team_id,omitempty"`
+	IsServiceAccount  bool       `json:"is_service_account"`
+	IsActive          bool       `json:"is_active"`
+	DeactivatedAt     *time.Time `json:"deactivated_at,omitempty"`
+	SessionsRevokedAt *time.Time `json:"sessions_revoked_at,omitempty"` // Tokens issued until then are rejected
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// UserSearch filters and paginates a user listing
+type UserSearch struct {
+	Query    string // Matches username or email, case-insensitive substring
+	Page     int    // 1-based
+	PageSize int
+}
+
+// UserPage is one page of a user listing
+type UserPage struct {
+	Users    []User `json:"users"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
 }
 
-// Role represents a user role
+// Role represents a user role.
+// A role inherits every permission granted to its parent role.
 type Role struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parent_id,omitempty"`
+	// RequiresMFA limits the role's grants, and those it inherits, to sessions
+	// that completed multi-factor authentication
+	RequiresMFA bool `json:"requires_mfa"`
 }
 
 // Permission represents an action a role can perform
 type Permission struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// RoleGrant is a permission attached to a role, as stored in role_permissions
+type RoleGrant struct {
+	PermissionID int64           `json:"permission_id"`
+	Permission   string          `json:"permission"`
+	Scope        PermissionScope `json:"scope"`
+	Effect       GrantEffect     `json:"effect"`
+	Condition    string          `json:"condition,omitempty"`
+}
+
+// DefaultOrgID is the organization new users join on registration
+const DefaultOrgID int64 = 1
+
+// Organization is a tenant. Role assignments and products belong to one organization.
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Group is a set of users in one organization; roles assigned to the group apply to every member
+type Group struct {
+	ID        int64     `json:"id"`
+	OrgID     int64     `json:"org_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoleSource says how a user came to hold a role
+type RoleSource string
+
+const (
+	// RoleSourceDirect is a role assigned to the user
+	RoleSourceDirect RoleSource = "direct"
+	// RoleSourceGroup is a role assigned to a group the user belongs to
+	RoleSourceGroup RoleSource = "group"
+	// RoleSourceInherited is a parent of another role the user holds
+	RoleSourceInherited RoleSource = "inherited"
+)
+
+// EffectiveRole is a role a user holds, with where it came from
+type EffectiveRole struct {
+	Role       Role       `json:"role"`
+	Source     RoleSource `json:"source"`
+	Via        string     `json:"via,omitempty"`         // Group name, or the role that inherits this one
+	ValidUntil *time.Time `json:"valid_until,omitempty"` // Set when the role comes from an expiring assignment
+}
+
+// RoleAssignment assigns a role to a user in one organization, optionally for a limited time
+type RoleAssignment struct {
+	UserID     int64      `json:"user_id"`
+	RoleID     int64      `json:"role_id"`
+	OrgID      int64      `json:"org_id"`
+	RoleName   string     `json:"role_name,omitempty"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"` // Nil never expires
+}
+
+// RefreshToken is a server-side record of an opaque refresh token. Only its
+// hash is stored. Tokens issued by rotating one another share a FamilyID.
+type RefreshToken struct {
+	ID        int64
+	TokenHash string
+	FamilyID  string
+	UserID    int64
+	OrgID     int64
+	ExpiresAt time.Time
+	UsedAt    *time.Time // Set once the token has been exchanged
+	RevokedAt *time.Time
+	MFA       bool // The login that started the family completed MFA
+	CreatedAt time.Time
+}
+
+// SigningKeyRecord is a stored token signing key
+type SigningKeyRecord struct {
+	KID              string
+	Algorithm        string
+	SealedPrivateKey string // PKCS#8 PEM encrypted with the key encryption key
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+}
+
+// RevokedToken is an access token revoked before its expiry, identified by its jti
+type RevokedToken struct {
+	JTI       string
+	UserID    int64
+	ExpiresAt time.Time
+}
+
+// LogoutRequest is the optional payload for logging out. When a refresh token
+// is given, its family is revoked as well.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ServiceAccount is a machine identity: a user without a password that
+// authenticates with the client_credentials grant. It lives in one organization.
+type ServiceAccount struct {
+	UserID      int64     `json:"user_id"`
+	OrgID       int64     `json:"org_id"`
+	Name        string    `json:"name"` // The user's username
+	ClientID    string    `json:"client_id"`
+	SecretHash  string    `json:"-"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   *int64    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// APIKeyPrefix starts every API key, so keys can be told apart from JWTs
+const APIKeyPrefix = "rbk_"
+
+// APIKey is a personal key that acts as its owner in one organization.
+// Only its hash is stored; the key itself is shown once, at creation.
+type APIKey struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	OrgID       int64      `json:"org_id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	KeyHash     string     `json:"-"`
+	Permissions []string   `json:"permissions,omitempty"` // Empty: all of the owner's permissions
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// OAuthClient is an application registered to sign users in through OIDC
+type OAuthClient struct {
+	ID           int64     `json:"id"`
+	ClientID     string    `json:"client_id"`
+	SecretHash   string    `json:"-"` // Empty for public clients
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// IsPublic reports whether the client has no secret and must rely on PKCE alone
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+// AuthorizationCode is a single-use code issued by /authorize. Only its hash is stored.
+type AuthorizationCode struct {
+	CodeHash       string
+	ClientID       string
+	UserID         int64
+	OrgID          int64
+	RedirectURI    string
+	Scope          string
+	Nonce          string
+	CodeChallenge  string // S256 of the client's code_verifier
+	AuthTime       time.Time
+	MFA            bool // The sign-in completed MFA
+	ExpiresAt      time.Time
+	UsedAt         *time.Time // Issue time of the access token below
+	AccessTokenJTI string     // jti of the access token issued when the code was used
+}
+
+// TOTPEnrollment is a user's TOTP secret. It is pending until EnabledAt is set.
+type TOTPEnrollment struct {
+	UserID       int64
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep *int64 // Time step of the last accepted code
+	CreatedAt    time.Time
+}
+
+// MFAChallenge links the password step of a login to its second step
+type MFAChallenge struct {
+	ID        int64
+	TokenHash string
+	UserID    int64
+	OrgID     int64
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// Login throttling scopes: failed logins are counted per account and per
+// client IP, and attempts at a second factor per user. Password reset requests
+// are counted per address (by hash) and per client IP.
+const (
+	ThrottleScopeAccount    = "account"
+	ThrottleScopeIP         = "ip"
+	ThrottleScopeMFA        = "mfa"
+	ThrottleScopeResetEmail = "reset_email"
+	ThrottleScopeResetIP    = "reset_ip"
+)
+
+// LoginFailures tracks recent failed logins for one account or client IP
+type LoginFailures struct {
+	Scope         string
+	Subject       string // User ID for ThrottleScopeAccount and ThrottleScopeMFA, address for ThrottleScopeIP
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// Audit event types
+const (
+	AuditRoleAssigned    = "role_assigned"
+	AuditRoleRevoked     = "role_revoked"
+	AuditRoleExpired     = "role_assignment_expired"
+	AuditUserDeactivated = "user_deactivated"
+	AuditUserActivated   = "user_activated"
+	AuditRefreshReuse    = "refresh_token_reused"
+	AuditSessionsRevoked = "sessions_revoked"
+
+	AuditServiceAccountCreated = "service_account_created"
+	AuditServiceAccountRotated = "service_account_secret_rotated"
+	AuditServiceAccountDeleted = "service_account_deleted"
+
+	AuditPasswordReset   = "password_reset"
+	AuditPasswordChanged = "password_changed"
+	AuditEmailVerified   = "email_verified"
+
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditIPLocked        = "login_ip_locked"
+	AuditMFALocked       = "mfa_locked"
+
+	AuditMFAEnabled          = "mfa_enabled"
+	AuditMFADisabled         = "mfa_disabled"
+	AuditRecoveryCodeUsed    = "mfa_recovery_code_used"
+	AuditRecoveryCodesIssued = "mfa_recovery_codes_issued"
+)
+
+// AuditEvent records a security-relevant change
+type AuditEvent struct {
+	ID          int64                  `json:"id"`
+	Type        string                 `json:"type"`
+	ActorUserID *int64                 `json:"actor_user_id,omitempty"` // Nil for system actions such as the expiry sweeper
+	UserID      *int64                 `json:"user_id,omitempty"`
+	OrgID       *int64                 `json:"org_id,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// PermissionScope restricts which resources a grant applies to
+type PermissionScope string
+
+const (
+	// ScopeAny grants the permission on every resource
+	ScopeAny PermissionScope = "any"
+	// ScopeOwn grants the permission only on resources the user created
+	ScopeOwn PermissionScope = "own"
+	// ScopeTeam grants the permission only on resources created by the user's team
+	ScopeTeam PermissionScope = "team"
+)
+
+// GrantEffect says whether a grant allows or denies a permission
+type GrantEffect string
+
+const (
+	// EffectAllow grants the permission
+	EffectAllow GrantEffect = "allow"
+	// EffectDeny blocks the permission, overriding any allow
+	EffectDeny GrantEffect = "deny"
+)
+
+// Grant is a permission rule that applies to a user, either through one of
+// their roles or attached to the user directly
+type Grant struct {
+	Permission string          `json:"permission"`
+	Scope      PermissionScope `json:"scope"`
+	Effect     GrantEffect     `json:"effect"`
+	Role       string          `json:"role,omitempty"` // Empty for grants attached directly to the user
+	Condition  string          `json:"condition,omitempty"`
+	// RequiresMFA is set when every role the grant is reached through requires MFA
+	RequiresMFA bool `json:"requires_mfa,omitempty"`
+}
+
+// String describes the grant for logs and error messages
+func (g Grant) String() string {
+	source := "user grant"
+	if g.Role != "" {
+		source = fmt.Sprintf("role %q", g.Role)
+	}
+	if g.Condition != "" {
+		return fmt.Sprintf("%s %q (scope %s, when %s) via %s", g.Effect, g.Permission, g.Scope, g.Condition, source)
+	}
+	return fmt.Sprintf("%s %q (scope %s) via %s", g.Effect, g.Permission, g.Scope, source)
+}
+
+// Decision is the outcome of a permission check
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Rule is the grant that decided the outcome: the allow that granted access
+	// or the deny that blocked it. Nil when nothing matched.
+	Rule *Grant `json:"rule,omitempty"`
+	// Blocked is set when an account-level policy refused the check before
+	// any grant was considered
+	Blocked string `json:"blocked,omitempty"`
+}
+
+// Reason explains the decision in one line
+func (d Decision) Reason() string {
+	switch {
+	case d.Blocked != "":
+		return "blocked: " + d.Blocked
+	case d.Rule != nil && d.Allowed:
+		return "allowed by " + d.Rule.String()
+	case d.Rule != nil:
+		return "denied by " + d.Rule.String()
+	default:
+		return "no matching grant"
+	}
+}
+
+// Grant evaluation outcomes reported by Explain
+const (
+	OutcomeAllow   = "allow"
+	OutcomeDeny    = "deny"
+	OutcomeSkipped = "skipped"
+)
+
+// GrantEvaluation records how one matching grant was evaluated
+type GrantEvaluation struct {
+	Grant           Grant  `json:"grant"`
+	ScopeApplies    bool   `json:"scope_applies"`
+	ConditionResult *bool  `json:"condition_result,omitempty"`
+	ConditionError  string `json:"condition_error,omitempty"`
+	// MFARequired is set when the grant was skipped because the session has not completed MFA
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	Outcome     string `json:"outcome"` // allow, deny or skipped
+}
+
+// Explanation is a permission decision with its full derivation
+type Explanation struct {
+	Decision    Decision          `json:"decision"`
+	Reason      string            `json:"reason"`
+	Roles       []EffectiveRole   `json:"roles"`
+	Evaluations []GrantEvaluation `json:"evaluations"`
+}
+
+// ResourceOwner identifies who owns a protected resource
+type ResourceOwner struct {
+	OrgID   int64
+	OwnerID int64
+	TeamID  *int64
 }
 
 // Product represents a resource to be protected
 type Product struct {
 	ID            int64     `json:"id"`
+	OrgID         int64     `json:"org_id"`
 	Name          string    `json:"name"`
 	Price         float64   `json:"price"`
 	CreatedByUserID int64     `json:"created_by_user"`
@@ -45,19 +417,287 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	OrgID    int64  `json:"org_id,omitempty"` // Optional; defaults to the user's first organization
 }
 
-// LoginResponse is the payload for a successful login
+// SwitchOrgRequest is the payload for switching the active organization
+type SwitchOrgRequest struct {
+	OrgID int64 `json:"org_id"`
+}
+
+// LoginResponse is the payload for a successful login or token refresh.
+// For users enrolled in MFA the password step only returns MFAToken, to be
+// exchanged at /login/mfa together with a code.
 type LoginResponse struct {
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"` // Access token lifetime in seconds
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+// MFALoginRequest is the second step of a login: the challenge token and a
+// TOTP or recovery code
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// ForgotPasswordRequest asks for a password reset token by email
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password with a token from a reset email
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordRequest replaces the caller's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Password policy violation codes
+const (
+	PasswordTooShort = "too_short"
+	PasswordTooLong  = "too_long"
+	PasswordCommon   = "common"
+	PasswordSimilar  = "similar_to_account"
+	PasswordBreached = "breached"
+)
+
+// PasswordViolation is one rule of the password policy that a password breaks
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// VerifyEmailRequest carries a token from a verification email
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
 }
 
+// MFACodeRequest carries a TOTP or recovery code confirming an MFA change
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TOTPSetup is returned when enrollment starts. ProvisioningURI is meant to
+// be rendered as a QR code; Secret is for manual entry.
+type TOTPSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodes are shown once; each can stand in for a TOTP code one time
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// MFAStatus describes a user's MFA enrollment
+type MFAStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// RefreshRequest is the payload for exchanging a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RoleRequest is the payload for creating or updating a role.
+// A nil ParentID means the role inherits from nothing.
+type RoleRequest struct {
+	Name        string `json:"name"`
+	ParentID    *int64 `json:"parent_id,omitempty"`
+	RequiresMFA bool   `json:"requires_mfa"`
+}
+
+// PermissionRequest is the payload for creating or updating a permission
+type PermissionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// GrantPermissionRequest is the payload for attaching a permission to a role.
+// Scope defaults to "any" and Effect to "allow".
+type GrantPermissionRequest struct {
+	PermissionID int64           `json:"permission_id"`
+	Scope        PermissionScope `json:"scope,omitempty"`
+	Effect       GrantEffect     `json:"effect,omitempty"`
+	Condition    string          `json:"condition,omitempty"`
+}
+
+// AssignRoleRequest is the payload for assigning a role to a user in the active organization.
+// ValidFrom defaults to now; a nil ValidUntil never expires.
+type AssignRoleRequest struct {
+	RoleID     int64      `json:"role_id"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+// CheckPermissionsRequest is the payload for checking several permissions at once
+type CheckPermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// CheckPermissionsResponse maps each requested permission to whether it is allowed
+type CheckPermissionsResponse struct {
+	Results map[string]bool `json:"results"`
+}
+
+// ExplainRequest is the payload for explaining a permission decision.
+// ResourceType and ResourceID are optional; IP and Time override the request environment.
+type ExplainRequest struct {
+	UserID       int64      `json:"user_id"`
+	Permission   string     `json:"permission"`
+	ResourceType string     `json:"resource_type,omitempty"`
+	ResourceID   int64      `json:"resource_id,omitempty"`
+	IP           string     `json:"ip,omitempty"`
+	Time         *time.Time `json:"time,omitempty"`
+}
+
+// CreateGroupRequest is the payload for creating a group
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+// GroupMemberRequest is the payload for adding a user to a group
+type GroupMemberRequest struct {
+	UserID int64 `json:"user_id"`
+}
+
+// GroupRoleRequest is the payload for assigning a role to a group
+type GroupRoleRequest struct {
+	RoleID int64 `json:"role_id"`
+}
+
 // CreateProductRequest is the payload for creating a product
 type CreateProductRequest struct {
 	Name  string  `json:"name"`
 	Price float64 `json:"price"`
 }
 
+// UpdateProductRequest is the payload for updating a product
+type UpdateProductRequest struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// ProductFilter narrows a product listing. Nil and zero fields do not filter.
+type ProductFilter struct {
+	Name      string   // Substring of the product name
+	MinPrice  *float64 // Inclusive
+	MaxPrice  *float64 // Inclusive
+	CreatedBy *int64
+	Cursor    string // NextCursor of the previous page; empty for the first page
+	Limit     int
+}
+
+// ProductPage is one page of a product listing, ordered by ID
+type ProductPage struct {
+	Products   []Product `json:"products"`
+	NextCursor string    `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// CreateOAuthClientRequest is the payload for registering an OIDC client
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Public       bool     `json:"public"` // No secret; PKCE only
+}
+
+// CreateOAuthClientResponse returns a new client. The secret is shown only once.
+type CreateOAuthClientResponse struct {
+	Client       OAuthClient `json:"client"`
+	ClientSecret string      `json:"client_secret,omitempty"`
+}
+
+// CreateServiceAccountRequest is the payload for creating a service account
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ServiceAccountCredentials returns a service account with its secret, shown only once
+type ServiceAccountCredentials struct {
+	ServiceAccount ServiceAccount `json:"service_account"`
+	ClientSecret   string         `json:"client_secret"`
+}
+
+// CreateAPIKeyRequest is the payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions"` // Optional subset of the owner's permissions
+	ExpiresAt   *time.Time `json:"expires_at"`  // Optional
+}
+
+// CreatedAPIKey returns a new API key. The key is shown only once.
+type CreatedAPIKey struct {
+	APIKey APIKey `json:"api_key"`
+	Key    string `json:"key"`
+}
+
+// AuthorizeRequest holds the parameters of an OIDC authorization request
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	OrgID               int64 // Optional org_id extension; defaults to the user's oldest membership
+}
+
+// TokenRequest holds the parameters of an OAuth2 token request
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+// TokenResponse is the OAuth2 token endpoint response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// UserInfo is the OIDC userinfo response
+type UserInfo struct {
+	Subject           string   `json:"sub"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	Roles             []string `json:"roles"`
+}
+
+// OIDCDiscovery is the OpenID provider metadata served at /.well-known/openid-configuration
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
 // --- GraphQL Client Structs ---
 
 // GraphQLRequest represents the JSON body we send in a POST request
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"log"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers account emails such as password resets and address verification
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to a log instead of sending them. It is meant for
+// development: links can be copied from the output or the file.
+type LogMailer struct {
+	logger *log.Logger
+}
+
+// NewLogMailer creates a LogMailer writing to w (a file, or os.Stderr)
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{logger: log.New(w, "", log.LstdFlags)}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Printf("Mail to %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
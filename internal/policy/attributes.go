@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"context"
+	"time"
+)
+
+// Attribute namespaces a condition can reference
+const (
+	NamespaceSubject  = "subject"
+	NamespaceResource = "resource"
+	NamespaceEnv      = "env"
+)
+
+var namespaces = map[string]struct{}{
+	NamespaceSubject:  {},
+	NamespaceResource: {},
+	NamespaceEnv:      {},
+}
+
+// Attributes holds the values a condition is evaluated against, keyed by
+// namespace and then by attribute name, e.g. attrs["env"]["ip"]
+type Attributes map[string]map[string]interface{}
+
+// Set stores a single attribute
+func (a Attributes) Set(namespace, name string, value interface{}) {
+	if a[namespace] == nil {
+		a[namespace] = map[string]interface{}{}
+	}
+	a[namespace][name] = value
+}
+
+// Environment describes the request a permission check is made for
+type Environment struct {
+	IP     string
+	Method string
+	Path   string
+	Time   time.Time
+}
+
+// Attributes exposes the environment under the "env" namespace
+func (e Environment) Attributes() map[string]interface{} {
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return map[string]interface{}{
+		"ip":      e.IP,
+		"method":  e.Method,
+		"path":    e.Path,
+		"hour":    t.Hour(),
+		"minute":  t.Minute(),
+		"weekday": t.Weekday().String(),
+		"date":    t.Format("2006-01-02"),
+		"unix":    t.Unix(),
+	}
+}
+
+type envKey struct{}
+
+// WithEnvironment attaches the request environment to ctx
+func WithEnvironment(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvironmentFrom returns the request environment attached to ctx.
+// Outside of a request only the time is known.
+func EnvironmentFrom(ctx context.Context) Environment {
+	if env, ok := ctx.Value(envKey{}).(Environment); ok {
+		return env
+	}
+	return Environment{Time: time.Now()}
+}
+
+// normalize converts attribute values to the types the evaluator works with:
+// every number becomes float64
+func normalize(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case *int64:
+		if n == nil {
+			return nil
+		}
+		return float64(*n)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,564 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxExpressionLength bounds the size of a condition
+	maxExpressionLength = 1024
+	// maxDepth bounds nesting so a hostile condition cannot blow the stack
+	maxDepth = 32
+)
+
+// ErrInvalidExpression is returned when a condition cannot be parsed
+var ErrInvalidExpression = errors.New("invalid condition expression")
+
+// Expression is a compiled condition.
+//
+// The language is deliberately small: literals (numbers, 'strings' or "strings",
+// true, false, [lists]), attribute references (subject.x, resource.x, env.x),
+// comparisons (== != < <= > >=), membership (in), boolean operators (&& || !),
+// parentheses and a fixed set of pure functions (see functions). There are no
+// loops, assignments or side effects, so evaluation always terminates.
+//
+// Examples:
+//
+//	env.hour >= 9 && env.hour < 17
+//	cidr(env.ip, "10.0.0.0/8")
+//	resource.price < 1000
+//	env.weekday in ["Saturday", "Sunday"]
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses a condition
+func Compile(source string) (*Expression, error) {
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidExpression, maxExpressionLength)
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, p.peek().text)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate runs the expression against attrs. The result must be a boolean.
+// Referencing an attribute that is not set is an error, so callers can fail closed.
+func (e *Expression) Evaluate(attrs Attributes) (bool, error) {
+	v, err := e.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q does not evaluate to a boolean", e.source)
+	}
+	return b, nil
+}
+
+// --- Lexer ---
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, src[start:i]})
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], src[i])
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidExpression)
+			}
+			tokens = append(tokens, token{tokenString, src[i+1 : i+1+end]})
+			i += end + 2
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '.' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, src[start:i]})
+		default:
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, token{tokenOp, two})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("<>!()[],", c) {
+				tokens = append(tokens, token{tokenOp, string(c)})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidExpression, c)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// --- Parser ---
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return fmt.Errorf("%w: expected %q", ErrInvalidExpression, op)
+	}
+	return nil
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrInvalidExpression)
+	}
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot(depth int) (node, error) {
+	if p.accept("!") {
+		if depth+1 > maxDepth {
+			return nil, fmt.Errorf("%w: nested too deeply", ErrInvalidExpression)
+		}
+		operand, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison(depth)
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (p *parser) parseComparison(depth int) (node, error) {
+	left, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokenOp && comparisonOps[t.text]:
+		p.next()
+	case t.kind == tokenIdent && t.text == "in":
+		p.next()
+	default:
+		return left, nil
+	}
+	right, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	// Lists can only be searched; catch the obvious cases before they are stored
+	_, leftList := left.(listNode)
+	_, rightList := right.(listNode)
+	if leftList || rightList && t.text != "in" {
+		return nil, fmt.Errorf("%w: %s does not take a list", ErrInvalidExpression, t.text)
+	}
+	return compareNode{op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad number %q", ErrInvalidExpression, t.text)
+		}
+		return literalNode{value: f}, nil
+	case tokenString:
+		return literalNode{value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		}
+		if p.accept("(") {
+			return p.parseCall(t.text, depth)
+		}
+		ns, name, ok := strings.Cut(t.text, ".")
+		if !ok || name == "" || strings.Contains(name, ".") {
+			return nil, fmt.Errorf("%w: attribute %q must look like namespace.name", ErrInvalidExpression, t.text)
+		}
+		if _, known := namespaces[ns]; !known {
+			return nil, fmt.Errorf("%w: unknown namespace %q", ErrInvalidExpression, ns)
+		}
+		return attributeNode{namespace: ns, name: name}, nil
+	case tokenOp:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			return p.parseList(depth)
+		}
+	}
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrInvalidExpression)
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, t.text)
+}
+
+func (p *parser) parseList(depth int) (node, error) {
+	var items []node
+	if p.accept("]") {
+		return listNode{items: items}, nil
+	}
+	for {
+		item, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept("]") {
+			return listNode{items: items}, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseCall(name string, depth int) (node, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown function %q", ErrInvalidExpression, name)
+	}
+	var args []node
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%w: %s takes %d arguments", ErrInvalidExpression, name, fn.arity)
+	}
+	return callNode{name: name, fn: fn, args: args}, nil
+}
+
+// --- AST ---
+
+type node interface {
+	eval(attrs Attributes) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(Attributes) (interface{}, error) {
+	return n.value, nil
+}
+
+type attributeNode struct {
+	namespace string
+	name      string
+}
+
+func (n attributeNode) eval(attrs Attributes) (interface{}, error) {
+	v, ok := attrs[n.namespace][n.name]
+	if !ok {
+		return nil, fmt.Errorf("attribute %s.%s is not available", n.namespace, n.name)
+	}
+	return normalize(v), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n listNode) eval(attrs Attributes) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(attrs Attributes) (interface{}, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, errors.New("! needs a boolean operand")
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) eval(attrs Attributes) (interface{}, error) {
+	l, err := evalBool(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit like Go does
+	if n.op == "&&" && !l || n.op == "||" && l {
+		return l, nil
+	}
+	return evalBool(n.right, attrs)
+}
+
+func evalBool(n node, attrs Attributes) (bool, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.New("&& and || need boolean operands")
+	}
+	return b, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(attrs Attributes) (interface{}, error) {
+	l, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		// Attributes can hold lists too, and comparing those would panic
+		if !isScalar(l) || !isScalar(r) {
+			return nil, fmt.Errorf("%s is not defined for lists", n.op)
+		}
+		return (l == r) == (n.op == "=="), nil
+	case "in":
+		list, ok := r.([]interface{})
+		if !ok {
+			return nil, errors.New("in needs a list on the right")
+		}
+		if !isScalar(l) {
+			return nil, errors.New("in cannot look for a list")
+		}
+		for _, item := range list {
+			if isScalar(item) && item == l {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	// Ordering works on two numbers or two strings
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %T", r)
+		}
+		return order(n.op, lv < rv, lv == rv), nil
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %T", r)
+		}
+		return order(n.op, lv < rv, lv == rv), nil
+	default:
+		return nil, fmt.Errorf("%s is not defined for %T", n.op, l)
+	}
+}
+
+// isScalar reports whether v is a value == can compare without panicking
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, bool, float64, string:
+		return true
+	default:
+		return false
+	}
+}
+
+func order(op string, less, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	default: // ">="
+		return !less
+	}
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n callNode) eval(attrs Attributes) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return n.fn.call(args)
+}
+
+// --- Functions ---
+
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+// functions are the only calls a condition may make
+var functions = map[string]function{
+	// cidr(ip, network) reports whether ip falls inside the CIDR network
+	"cidr": {arity: 2, call: func(args []interface{}) (interface{}, error) {
+		ipStr, ok1 := args[0].(string)
+		cidr, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, errors.New("cidr needs two strings")
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("cidr: %w", err)
+		}
+		ip := net.ParseIP(ipStr)
+		return ip != nil && network.Contains(ip), nil
+	}},
+	// lower(s) lower-cases a string
+	"lower": {arity: 1, call: func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, errors.New("lower needs a string")
+		}
+		return strings.ToLower(s), nil
+	}},
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func testAttributes() Attributes {
+	attrs := Attributes{}
+	attrs.Set(NamespaceSubject, "id", int64(7))
+	attrs.Set(NamespaceSubject, "username", "Alice")
+	attrs.Set(NamespaceSubject, "tags", []interface{}{"a", "b"})
+	attrs.Set(NamespaceEnv, "ip", "10.1.2.3")
+	attrs.Set(NamespaceEnv, "hour", 10)
+	attrs.Set(NamespaceEnv, "weekday", "Saturday")
+	attrs.Set(NamespaceResource, "price", 250.0)
+	return attrs
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`true`, true},
+		{`!false`, true},
+		{`1 == 1`, true},
+		{`1 != 1`, false},
+		{`'a' == "a"`, true},
+		{`1 == "1"`, false},
+		{`subject.id == 7`, true},
+		{`env.hour >= 9 && env.hour < 17`, true},
+		{`env.hour > 10 || env.hour <= 9`, false},
+		{`"abc" < "abd"`, true},
+		{`resource.price < 1000`, true},
+		{`env.weekday in ["Saturday", "Sunday"]`, true},
+		{`env.weekday in []`, false},
+		{`1 in [[1], 2]`, false},
+		{`cidr(env.ip, "10.0.0.0/8")`, true},
+		{`cidr(env.ip, "192.168.0.0/16")`, false},
+		{`lower(subject.username) == "alice"`, true},
+		{`(1 < 2) == true`, true},
+		// The right side is not evaluated once the result is known
+		{`false && env.missing == 1`, false},
+		{`true || env.missing == 1`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Compile(tt.source)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.source, err)
+			continue
+		}
+		got, err := expr.Evaluate(testAttributes())
+		if err != nil {
+			t.Errorf("Evaluate(%q): %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	tests := []string{
+		``,
+		`1 ==`,
+		`(1 == 1`,
+		`'unterminated`,
+		`1 # 1`,
+		`user.id == 1`,
+		`subject == 1`,
+		`subject.a.b == 1`,
+		`nope(1)`,
+		`cidr("10.0.0.1")`,
+		`1 == 1 2`,
+		`[1] == [1]`,
+		`[1] != 1`,
+		`1 == [1]`,
+		`[1] in [[1]]`,
+		`[1] < 2`,
+		`subject.tags != ["a", "b"]`,
+	}
+	for _, source := range tests {
+		if _, err := Compile(source); !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("Compile(%q) = %v, want ErrInvalidExpression", source, err)
+		}
+	}
+}
+
+func TestCompileRejectsDeepNesting(t *testing.T) {
+	source := ""
+	for i := 0; i <= maxDepth; i++ {
+		source += "!"
+	}
+	if _, err := Compile(source + "true"); !errors.Is(err, ErrInvalidExpression) {
+		t.Errorf("Compile of %d nested nots = %v, want ErrInvalidExpression", maxDepth+1, err)
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []string{
+		`env.missing == 1`,
+		`1`,
+		`!1`,
+		`1 && true`,
+		`1 in 1`,
+		`1 < "a"`,
+		`true < false`,
+		`cidr(env.ip, "not a network")`,
+		`lower(1) == "1"`,
+		// Lists from attributes cannot be caught when compiling
+		`subject.tags == subject.tags`,
+		`subject.tags in [subject.tags]`,
+	}
+	for _, source := range tests {
+		expr, err := Compile(source)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", source, err)
+			continue
+		}
+		if got, err := expr.Evaluate(testAttributes()); err == nil {
+			t.Errorf("Evaluate(%q) = %v, want an error", source, got)
+		}
+	}
+}
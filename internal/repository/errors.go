@@ -3,4 +3,10 @@ package repository
 import "errors"
 
 // ErrNotFound is returned when a resource is not found
-var ErrNotFound = errors.New("not found")
\ No newline at end of file
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate is returned when a unique value (such as a name) is already taken
+var ErrDuplicate = errors.New("already exists")
+
+// ErrRoleCycle is returned when a role hierarchy change would create a cycle
+var ErrRoleCycle = errors.New("role hierarchy cycle")
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"rbac/internal/domain"
+	"time"
 )
 
 // DBTX is an interface for both *sql.DB and *sql.Tx
@@ -14,24 +15,253 @@ type DBTX interface {
 	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
 }
 
+// Transactor runs a unit of work in one database transaction
+type Transactor interface {
+	// WithinTx calls fn with repositories bound to a new transaction, and
+	// commits it if fn returns nil. Any error rolls every write back.
+	WithinTx(ctx context.Context, fn func(tx TxRepositories) error) error
+}
+
+// TxRepositories are the repositories available inside a transaction
+type TxRepositories struct {
+	Users           UserRepository
+	Organizations   OrganizationRepository
+	RefreshTokens   RefreshTokenRepository
+	ServiceAccounts ServiceAccountRepository
+	LoginFailures   LoginFailureRepository
+}
+
 // UserRepository defines the methods for interacting with user data
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	FindByUsername(ctx context.Context, username string) (*domain.User, error)
 	FindByID(ctx context.Context, id int64) (*domain.User, error)
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	UpdatePassword(ctx context.Context, userID int64, passwordHash string) error
+	// UpdatePasswordIfUnchanged replaces oldHash with newHash, returning
+	// ErrNotFound if the password was changed since oldHash was read
+	UpdatePasswordIfUnchanged(ctx context.Context, userID int64, oldHash, newHash string) error
+	// MarkEmailVerified records that the user proved ownership of email. It
+	// returns ErrNotFound if the user's address is no longer email.
+	MarkEmailVerified(ctx context.Context, userID int64, email string, at time.Time) error
+	// Search lists members of orgID matching search, returning the page and the total match count
+	Search(ctx context.Context, orgID int64, search domain.UserSearch) ([]domain.User, int, error)
+	SetActive(ctx context.Context, userID int64, active bool) error
+	// RevokeSessions rejects every token issued to the user until at, which
+	// must come from the clock that sets the tokens' iat
+	RevokeSessions(ctx context.Context, userID int64, at time.Time) error
 	// RBAC-specific
-	AssignRole(ctx context.Context, userID, roleID int64) error
-	GetUserPermissions(ctx context.Context, userID int64) ([]string, error)
+	// AssignRole assigns a role from validFrom on, with no end
+	AssignRole(ctx context.Context, userID, roleID, orgID int64, validFrom time.Time) error
+	RevokeRole(ctx context.Context, userID, roleID, orgID int64) error
+	// ListRoleAssignments lists the user's direct assignments in orgID, including inactive windows
+	ListRoleAssignments(ctx context.Context, userID, orgID int64) ([]domain.RoleAssignment, error)
+	// AssignTemporaryRole assigns a role for a validity window, replacing any existing window
+	AssignTemporaryRole(ctx context.Context, assignment domain.RoleAssignment) error
+	// ListExpiredRoles returns assignments whose valid_until is at or before now
+	ListExpiredRoles(ctx context.Context, now time.Time, limit int) ([]domain.RoleAssignment, error)
+	// DeleteExpiredRole removes an assignment if it is still expired at now
+	DeleteExpiredRole(ctx context.Context, assignment domain.RoleAssignment, now time.Time) (bool, error)
+	// GetUserPermissions lists the user's grants in orgID from assignments valid at now
+	GetUserPermissions(ctx context.Context, userID, orgID int64, now time.Time) ([]domain.Grant, error)
+	// NextValidityChange returns the earliest time after now at which one of the
+	// user's assignments in orgID starts or ends, or nil if none will
+	NextValidityChange(ctx context.Context, userID, orgID int64, now time.Time) (*time.Time, error)
+	// GetPlatformPermissions lists the names of the permissions granted to the
+	// user outside of any organization
+	GetPlatformPermissions(ctx context.Context, userID int64) ([]string, error)
+	// GetEffectiveRoles lists direct, group-derived and inherited roles with
+	// their source, from assignments valid at now
+	GetEffectiveRoles(ctx context.Context, userID, orgID int64, now time.Time) ([]domain.EffectiveRole, error)
 }
 
 // RoleRepository defines methods for roles and permissions
 type RoleRepository interface {
 	FindByName(ctx context.Context, name string) (*domain.Role, error)
+	FindByID(ctx context.Context, id int64) (*domain.Role, error)
+	List(ctx context.Context) ([]domain.Role, error)
+	Create(ctx context.Context, role *domain.Role) error
+	Rename(ctx context.Context, roleID int64, name string) error
+	SetRequiresMFA(ctx context.Context, roleID int64, requiresMFA bool) error
+	Delete(ctx context.Context, roleID int64) error
+	// SetParent makes the role inherit from parentID (nil clears the parent)
+	SetParent(ctx context.Context, roleID int64, parentID *int64) error
+
+	// Permissions
+	FindPermissionByID(ctx context.Context, id int64) (*domain.Permission, error)
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	CreatePermission(ctx context.Context, perm *domain.Permission) error
+	UpdatePermission(ctx context.Context, perm *domain.Permission) error
+	DeletePermission(ctx context.Context, id int64) error
+
+	// Role grants (role_permissions)
+	ListGrants(ctx context.Context, roleID int64) ([]domain.RoleGrant, error)
+	// GrantPermission attaches a permission to a role, replacing an existing grant of it
+	GrantPermission(ctx context.Context, roleID int64, grant domain.RoleGrant) error
+	RevokePermission(ctx context.Context, roleID, permissionID int64) error
+}
+
+// OrganizationRepository defines methods for organizations and their members
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *domain.Organization) error
+	FindByID(ctx context.Context, id int64) (*domain.Organization, error)
+	AddMember(ctx context.Context, orgID, userID int64) error
+	IsMember(ctx context.Context, orgID, userID int64) (bool, error)
+	ListForUser(ctx context.Context, userID int64) ([]domain.Organization, error)
+}
+
+// GroupRepository defines methods for user groups and their role assignments
+type GroupRepository interface {
+	Create(ctx context.Context, group *domain.Group) error
+	FindByID(ctx context.Context, id int64) (*domain.Group, error)
+	ListByOrg(ctx context.Context, orgID int64) ([]domain.Group, error)
+	AddMember(ctx context.Context, groupID, userID int64) error
+	RemoveMember(ctx context.Context, groupID, userID int64) error
+	AssignRole(ctx context.Context, groupID, roleID int64) error
+	RevokeRole(ctx context.Context, groupID, roleID int64) error
+}
+
+// AuditRepository stores audit events
+type AuditRepository interface {
+	Record(ctx context.Context, event *domain.AuditEvent) error
+}
+
+// RefreshTokenRepository stores refresh tokens by hash
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *domain.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	// MarkUsed marks an unused, unrevoked token as used. It returns ErrNotFound
+	// if the token was already used or revoked, so only one exchange can win.
+	MarkUsed(ctx context.Context, id int64) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
+
+// SigningKeyRepository stores token signing keys
+type SigningKeyRepository interface {
+	Create(ctx context.Context, key *domain.SigningKeyRecord) error
+	// ListValid returns keys that have not expired, newest first
+	ListValid(ctx context.Context) ([]domain.SigningKeyRecord, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// TokenRevocationRepository stores access tokens revoked before expiry
+type TokenRevocationRepository interface {
+	Revoke(ctx context.Context, token domain.RevokedToken) error
+	// ListSince returns unexpired revocations recorded at or after since
+	ListSince(ctx context.Context, since time.Time) ([]domain.RevokedToken, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// ServiceAccountRepository stores service account credentials. The account's
+// user row is created through UserRepository.
+type ServiceAccountRepository interface {
+	Create(ctx context.Context, account *domain.ServiceAccount) error
+	FindByClientID(ctx context.Context, clientID string) (*domain.ServiceAccount, error)
+	FindByUserID(ctx context.Context, userID int64) (*domain.ServiceAccount, error)
+	ListByOrg(ctx context.Context, orgID int64) ([]domain.ServiceAccount, error)
+	UpdateSecret(ctx context.Context, userID int64, secretHash string) error
+	// Delete removes the service account together with its user
+	Delete(ctx context.Context, userID int64) error
+}
+
+// APIKeyRepository stores API keys by hash
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error)
+	// Delete removes a key, returning ErrNotFound unless userID owns it
+	Delete(ctx context.Context, id, userID int64) error
+	DeleteAllForUser(ctx context.Context, userID int64) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+// ActionTokenRepository remembers used password reset and email verification tokens
+type ActionTokenRepository interface {
+	// MarkUsed records a token's jti, returning ErrDuplicate if it was already used
+	MarkUsed(ctx context.Context, jti string, expiresAt time.Time) error
+	// DeleteExpired forgets tokens that have expired anyway
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// LoginFailureRepository counts failed logins per account and client IP
+type LoginFailureRepository interface {
+	Find(ctx context.Context, scope, subject string) (*domain.LoginFailures, error)
+	// FindForUpdate is Find that also locks the row until the transaction ends
+	FindForUpdate(ctx context.Context, scope, subject string) (*domain.LoginFailures, error)
+	// Init creates the subject's row with no failures, unless it exists
+	Init(ctx context.Context, scope, subject string, at time.Time) error
+	// RecordFailure counts one more failure at time at, starting over when the
+	// previous one is older than since, and returns the updated record
+	RecordFailure(ctx context.Context, scope, subject string, at, since time.Time) (*domain.LoginFailures, error)
+	// Lock locks the subject out until the given time and resets its failure count
+	Lock(ctx context.Context, scope, subject string, until time.Time) error
+	// Release takes back one failure, counted for an attempt that succeeded
+	Release(ctx context.Context, scope, subject string) error
+	Delete(ctx context.Context, scope, subject string) error
+	// DeleteStale removes subjects without a failure since before that are not locked out
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+// MFARepository stores TOTP enrollments and recovery codes
+type MFARepository interface {
+	FindTOTP(ctx context.Context, userID int64) (*domain.TOTPEnrollment, error)
+	// SaveTOTP starts, or restarts, a pending enrollment with a new secret
+	SaveTOTP(ctx context.Context, userID int64, secret string) error
+	EnableTOTP(ctx context.Context, userID int64, at time.Time) error
+	// UseTOTPStep records the time step of an accepted code. It returns
+	// ErrNotFound if that step or a later one was already used, so each code works once.
+	UseTOTPStep(ctx context.Context, userID, step int64) error
+	// DeleteTOTP removes the enrollment and the user's recovery codes
+	DeleteTOTP(ctx context.Context, userID int64) error
+
+	// ReplaceRecoveryCodes discards the user's recovery codes and stores new ones by hash
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	// UseRecoveryCode marks an unused code as used, returning ErrNotFound otherwise
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int, error)
+}
+
+// MFAChallengeRepository stores logins waiting for their second factor
+type MFAChallengeRepository interface {
+	Create(ctx context.Context, challenge *domain.MFAChallenge) error
+	FindByHash(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error)
+	// RecordAttempt counts an attempt at an unused challenge, returning
+	// ErrNotFound once it was used or had max attempts
+	RecordAttempt(ctx context.Context, id int64, max int) error
+	// MarkUsed returns ErrNotFound if the challenge was already used, so only one exchange can win
+	MarkUsed(ctx context.Context, id int64) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// OAuthClientRepository stores registered OIDC clients
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *domain.OAuthClient) error
+	FindByClientID(ctx context.Context, clientID string) (*domain.OAuthClient, error)
+	List(ctx context.Context) ([]domain.OAuthClient, error)
+	Delete(ctx context.Context, clientID string) error
+}
+
+// AuthorizationCodeRepository stores OIDC authorization codes by hash
+type AuthorizationCodeRepository interface {
+	Create(ctx context.Context, code *domain.AuthorizationCode) error
+	FindByHash(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error)
+	// MarkUsed records the jti and issue time of the access token issued for the
+	// code. It returns ErrNotFound if the code was already used, so only one
+	// exchange can win.
+	MarkUsed(ctx context.Context, codeHash, accessTokenJTI string, usedAt time.Time) error
 }
 
 // ProductRepository defines the methods for interacting with product data
 type ProductRepository interface {
 	Create(ctx context.Context, product *domain.Product) error
 	FindByID(ctx context.Context, id int64) (*domain.Product, error)
+	// List returns up to limit products of an organization with IDs above afterID,
+	// ordered by ID. filter.Cursor and filter.Limit are ignored.
+	List(ctx context.Context, orgID int64, filter domain.ProductFilter, afterID int64, limit int) ([]domain.Product, error)
+	Update(ctx context.Context, product *domain.Product) error
+	Delete(ctx context.Context, id int64) error
+	// FindOwner returns the creator of a product and the creator's team
+	FindOwner(ctx context.Context, id int64) (*domain.ResourceOwner, error)
 	// Add other CRUD methods (FindAll, Update, Delete) as needed
 }
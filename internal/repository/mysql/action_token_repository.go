@@ -0,0 +1,30 @@
+package mysql
+
+import (
+	"context"
+	"rbac/internal/repository"
+	"time"
+)
+
+type mysqlActionTokenRepository struct {
+	db repository.DBTX
+}
+
+// NewActionTokenRepository creates a new ActionTokenRepository
+func NewActionTokenRepository(db repository.DBTX) repository.ActionTokenRepository {
+	return &mysqlActionTokenRepository{db: db}
+}
+
+func (r *mysqlActionTokenRepository) MarkUsed(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := "INSERT INTO used_action_tokens (jti, expires_at) VALUES (?, ?)"
+	_, err := r.db.ExecContext(ctx, query, jti, expiresAt)
+	return translateError(err)
+}
+
+func (r *mysqlActionTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM used_action_tokens WHERE expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"time"
+)
+
+// apiKeyColumns is the column list scanAPIKey expects
+const apiKeyColumns = "id, user_id, org_id, name, prefix, key_hash, permissions, expires_at, last_used_at, created_at"
+
+type mysqlAPIKeyRepository struct {
+	db repository.DBTX
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository
+func NewAPIKeyRepository(db repository.DBTX) repository.APIKeyRepository {
+	return &mysqlAPIKeyRepository{db: db}
+}
+
+func (r *mysqlAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	var permissions []byte
+	if len(key.Permissions) > 0 {
+		var err error
+		if permissions, err = json.Marshal(key.Permissions); err != nil {
+			return err
+		}
+	}
+
+	query := "INSERT INTO api_keys (user_id, org_id, name, prefix, key_hash, permissions, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, key.UserID, key.OrgID, key.Name, key.Prefix, key.KeyHash, permissions, key.ExpiresAt)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	key.ID = id
+	return nil
+}
+
+func (r *mysqlAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = ?"
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+func (r *mysqlAPIKeyRepository) ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE user_id = ? ORDER BY id"
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *mysqlAPIKeyRepository) Delete(ctx context.Context, id, userID int64) error {
+	return execAffectingRow(ctx, r.db, "DELETE FROM api_keys WHERE id = ? AND user_id = ?", id, userID)
+}
+
+func (r *mysqlAPIKeyRepository) DeleteAllForUser(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM api_keys WHERE user_id = ?", userID)
+	return err
+}
+
+func (r *mysqlAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", at, id)
+	return err
+}
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	var key domain.APIKey
+	var permissions []byte
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.UserID, &key.OrgID, &key.Name, &key.Prefix, &key.KeyHash,
+		&permissions, &expiresAt, &lastUsedAt, &key.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if permissions != nil {
+		if err := json.Unmarshal(permissions, &key.Permissions); err != nil {
+			return nil, err
+		}
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	return &key, nil
+}
@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+type mysqlAuditRepository struct {
+	db repository.DBTX
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db repository.DBTX) repository.AuditRepository {
+	return &mysqlAuditRepository{db: db}
+}
+
+func (r *mysqlAuditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	var details []byte
+	if len(event.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(event.Details); err != nil {
+			return err
+		}
+	}
+
+	query := "INSERT INTO audit_events (event_type, actor_user_id, user_id, org_id, details) VALUES (?, ?, ?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, event.Type, event.ActorUserID, event.UserID, event.OrgID, details)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	return nil
+}
@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"time"
+)
+
+type mysqlAuthorizationCodeRepository struct {
+	db repository.DBTX
+}
+
+// NewAuthorizationCodeRepository creates a new AuthorizationCodeRepository
+func NewAuthorizationCodeRepository(db repository.DBTX) repository.AuthorizationCodeRepository {
+	return &mysqlAuthorizationCodeRepository{db: db}
+}
+
+func (r *mysqlAuthorizationCodeRepository) Create(ctx context.Context, code *domain.AuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes
+			(code_hash, client_id, user_id, org_id, redirect_uri, scope, nonce, code_challenge, auth_time, mfa, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	var nonce sql.NullString
+	if code.Nonce != "" {
+		nonce = sql.NullString{String: code.Nonce, Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx, query, code.CodeHash, code.ClientID, code.UserID, code.OrgID,
+		code.RedirectURI, code.Scope, nonce, code.CodeChallenge, code.AuthTime, code.MFA, code.ExpiresAt)
+	return translateError(err)
+}
+
+func (r *mysqlAuthorizationCodeRepository) FindByHash(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	query := `
+		SELECT code_hash, client_id, user_id, org_id, redirect_uri, scope, nonce, code_challenge, auth_time, mfa, expires_at, used_at, access_token_jti
+		FROM oauth_authorization_codes
+		WHERE code_hash = ?
+	`
+	var code domain.AuthorizationCode
+	var nonce sql.NullString
+	var usedAt sql.NullTime
+	var jti sql.NullString
+	err := r.db.QueryRowContext(ctx, query, codeHash).Scan(
+		&code.CodeHash, &code.ClientID, &code.UserID, &code.OrgID, &code.RedirectURI, &code.Scope,
+		&nonce, &code.CodeChallenge, &code.AuthTime, &code.MFA, &code.ExpiresAt, &usedAt, &jti,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	code.Nonce = nonce.String
+	if usedAt.Valid {
+		code.UsedAt = &usedAt.Time
+	}
+	code.AccessTokenJTI = jti.String
+	return &code, nil
+}
+
+func (r *mysqlAuthorizationCodeRepository) MarkUsed(ctx context.Context, codeHash, accessTokenJTI string, usedAt time.Time) error {
+	query := "UPDATE oauth_authorization_codes SET used_at = ?, access_token_jti = ? WHERE code_hash = ? AND used_at IS NULL"
+	return execAffectingRow(ctx, r.db, query, usedAt, accessTokenJTI, codeHash)
+}
@@ -1,13 +1,19 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"rbac/internal/repository"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
+// mysqlErrDuplicateEntry is the MySQL error number for a unique key violation
+const mysqlErrDuplicateEntry = 1062
+
 // NewDB creates a new database connection
 func NewDB(dataSourceName string) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dataSourceName)
@@ -21,4 +27,65 @@ func NewDB(dataSourceName string) (*sql.DB, error) {
 
 	log.Println("Database connection established")
 	return db, nil
-}
\ No newline at end of file
+}
+
+type mysqlTransactor struct {
+	db *sql.DB
+}
+
+// NewTransactor creates a Transactor running transactions on db
+func NewTransactor(db *sql.DB) repository.Transactor {
+	return &mysqlTransactor{db: db}
+}
+
+func (t *mysqlTransactor) WithinTx(ctx context.Context, fn func(tx repository.TxRepositories) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// A no-op once committed; otherwise undoes everything, even after a panic
+	defer tx.Rollback()
+
+	err = fn(repository.TxRepositories{
+		Users:           NewUserRepository(tx),
+		Organizations:   NewOrganizationRepository(tx),
+		RefreshTokens:   NewRefreshTokenRepository(tx),
+		ServiceAccounts: NewServiceAccountRepository(tx),
+		LoginFailures:   NewLoginFailureRepository(tx),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execAffectingRow runs a write that must touch at least one row,
+// returning repository.ErrNotFound when it touches none
+func execAffectingRow(ctx context.Context, db repository.DBTX, query string, args ...interface{}) error {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// translateError maps driver errors callers care about to repository errors
+func translateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return repository.ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+type mysqlGroupRepository struct {
+	db repository.DBTX
+}
+
+// NewGroupRepository creates a new GroupRepository
+func NewGroupRepository(db repository.DBTX) repository.GroupRepository {
+	return &mysqlGroupRepository{db: db}
+}
+
+func (r *mysqlGroupRepository) Create(ctx context.Context, group *domain.Group) error {
+	query := "INSERT INTO user_groups (org_id, name) VALUES (?, ?)"
+	res, err := r.db.ExecContext(ctx, query, group.OrgID, group.Name)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	group.ID = id
+	return nil
+}
+
+func (r *mysqlGroupRepository) FindByID(ctx context.Context, id int64) (*domain.Group, error) {
+	query := "SELECT id, org_id, name, created_at FROM user_groups WHERE id = ?"
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var group domain.Group
+	err := row.Scan(&group.ID, &group.OrgID, &group.Name, &group.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *mysqlGroupRepository) ListByOrg(ctx context.Context, orgID int64) ([]domain.Group, error) {
+	query := "SELECT id, org_id, name, created_at FROM user_groups WHERE org_id = ? ORDER BY name"
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []domain.Group
+	for rows.Next() {
+		var group domain.Group
+		if err := rows.Scan(&group.ID, &group.OrgID, &group.Name, &group.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+func (r *mysqlGroupRepository) AddMember(ctx context.Context, groupID, userID int64) error {
+	query := "INSERT IGNORE INTO user_group_members (group_id, user_id) VALUES (?, ?)"
+	_, err := r.db.ExecContext(ctx, query, groupID, userID)
+	return err
+}
+
+func (r *mysqlGroupRepository) RemoveMember(ctx context.Context, groupID, userID int64) error {
+	query := "DELETE FROM user_group_members WHERE group_id = ? AND user_id = ?"
+	return execAffectingRow(ctx, r.db, query, groupID, userID)
+}
+
+func (r *mysqlGroupRepository) AssignRole(ctx context.Context, groupID, roleID int64) error {
+	query := "INSERT IGNORE INTO group_roles (group_id, role_id) VALUES (?, ?)"
+	_, err := r.db.ExecContext(ctx, query, groupID, roleID)
+	return err
+}
+
+func (r *mysqlGroupRepository) RevokeRole(ctx context.Context, groupID, roleID int64) error {
+	query := "DELETE FROM group_roles WHERE group_id = ? AND role_id = ?"
+	return execAffectingRow(ctx, r.db, query, groupID, roleID)
+}
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"time"
+)
+
+// loginFailureColumns is the column list find expects
+const loginFailureColumns = "scope, subject, failures, last_failure_at, locked_until"
+
+type mysqlLoginFailureRepository struct {
+	db repository.DBTX
+}
+
+// NewLoginFailureRepository creates a new LoginFailureRepository
+func NewLoginFailureRepository(db repository.DBTX) repository.LoginFailureRepository {
+	return &mysqlLoginFailureRepository{db: db}
+}
+
+func (r *mysqlLoginFailureRepository) Find(ctx context.Context, scope, subject string) (*domain.LoginFailures, error) {
+	return r.find(ctx, "SELECT "+loginFailureColumns+" FROM login_failures WHERE scope = ? AND subject = ?", scope, subject)
+}
+
+func (r *mysqlLoginFailureRepository) FindForUpdate(ctx context.Context, scope, subject string) (*domain.LoginFailures, error) {
+	return r.find(ctx, "SELECT "+loginFailureColumns+" FROM login_failures WHERE scope = ? AND subject = ? FOR UPDATE", scope, subject)
+}
+
+func (r *mysqlLoginFailureRepository) find(ctx context.Context, query, scope, subject string) (*domain.LoginFailures, error) {
+	var record domain.LoginFailures
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, scope, subject).Scan(
+		&record.Scope, &record.Subject, &record.Failures, &record.LastFailureAt, &lockedUntil,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		record.LockedUntil = &lockedUntil.Time
+	}
+	return &record, nil
+}
+
+func (r *mysqlLoginFailureRepository) Init(ctx context.Context, scope, subject string, at time.Time) error {
+	query := "INSERT IGNORE INTO login_failures (scope, subject, failures, last_failure_at) VALUES (?, ?, 0, ?)"
+	_, err := r.db.ExecContext(ctx, query, scope, subject, at)
+	return err
+}
+
+func (r *mysqlLoginFailureRepository) RecordFailure(ctx context.Context, scope, subject string, at, since time.Time) (*domain.LoginFailures, error) {
+	// Assignments run left to right, so failures still sees the previous last_failure_at
+	query := `
+		INSERT INTO login_failures (scope, subject, failures, last_failure_at) VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(last_failure_at < ?, 1, failures + 1),
+			last_failure_at = VALUES(last_failure_at)
+	`
+	if _, err := r.db.ExecContext(ctx, query, scope, subject, at, since); err != nil {
+		return nil, err
+	}
+	return r.Find(ctx, scope, subject)
+}
+
+func (r *mysqlLoginFailureRepository) Lock(ctx context.Context, scope, subject string, until time.Time) error {
+	query := "UPDATE login_failures SET locked_until = ?, failures = 0 WHERE scope = ? AND subject = ?"
+	return execAffectingRow(ctx, r.db, query, until, scope, subject)
+}
+
+func (r *mysqlLoginFailureRepository) Release(ctx context.Context, scope, subject string) error {
+	query := "UPDATE login_failures SET failures = GREATEST(failures - 1, 0) WHERE scope = ? AND subject = ?"
+	_, err := r.db.ExecContext(ctx, query, scope, subject)
+	return err
+}
+
+func (r *mysqlLoginFailureRepository) Delete(ctx context.Context, scope, subject string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_failures WHERE scope = ? AND subject = ?", scope, subject)
+	return err
+}
+
+func (r *mysqlLoginFailureRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_failures
+		WHERE last_failure_at < ? AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+	`
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
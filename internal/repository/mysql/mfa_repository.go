@@ -0,0 +1,159 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"strings"
+	"time"
+)
+
+type mysqlMFARepository struct {
+	db repository.DBTX
+}
+
+// NewMFARepository creates a new MFARepository
+func NewMFARepository(db repository.DBTX) repository.MFARepository {
+	return &mysqlMFARepository{db: db}
+}
+
+func (r *mysqlMFARepository) FindTOTP(ctx context.Context, userID int64) (*domain.TOTPEnrollment, error) {
+	query := "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_totp WHERE user_id = ?"
+	var enrollment domain.TOTPEnrollment
+	var enabledAt sql.NullTime
+	var lastUsedStep sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&enrollment.UserID, &enrollment.Secret, &enabledAt, &lastUsedStep, &enrollment.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if enabledAt.Valid {
+		enrollment.EnabledAt = &enabledAt.Time
+	}
+	if lastUsedStep.Valid {
+		enrollment.LastUsedStep = &lastUsedStep.Int64
+	}
+	return &enrollment, nil
+}
+
+func (r *mysqlMFARepository) SaveTOTP(ctx context.Context, userID int64, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled_at = NULL, last_used_step = NULL, created_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, secret)
+	return err
+}
+
+func (r *mysqlMFARepository) EnableTOTP(ctx context.Context, userID int64, at time.Time) error {
+	return execAffectingRow(ctx, r.db, "UPDATE user_totp SET enabled_at = ? WHERE user_id = ?", at, userID)
+}
+
+func (r *mysqlMFARepository) UseTOTPStep(ctx context.Context, userID, step int64) error {
+	query := "UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)"
+	return execAffectingRow(ctx, r.db, query, step, userID, step)
+}
+
+func (r *mysqlMFARepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return execAffectingRow(ctx, r.db, "DELETE FROM user_totp WHERE user_id = ?", userID)
+}
+
+func (r *mysqlMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(codeHashes))
+	args := make([]interface{}, 0, 2*len(codeHashes))
+	for i, hash := range codeHashes {
+		placeholders[i] = "(?, ?)"
+		args = append(args, userID, hash)
+	}
+	query := "INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES " + strings.Join(placeholders, ", ")
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return translateError(err)
+}
+
+func (r *mysqlMFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	query := "UPDATE mfa_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = ? AND code_hash = ? AND used_at IS NULL"
+	return execAffectingRow(ctx, r.db, query, userID, codeHash)
+}
+
+func (r *mysqlMFARepository) CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL"
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+type mysqlMFAChallengeRepository struct {
+	db repository.DBTX
+}
+
+// NewMFAChallengeRepository creates a new MFAChallengeRepository
+func NewMFAChallengeRepository(db repository.DBTX) repository.MFAChallengeRepository {
+	return &mysqlMFAChallengeRepository{db: db}
+}
+
+func (r *mysqlMFAChallengeRepository) Create(ctx context.Context, challenge *domain.MFAChallenge) error {
+	query := "INSERT INTO mfa_challenges (token_hash, user_id, org_id, expires_at) VALUES (?, ?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, challenge.TokenHash, challenge.UserID, challenge.OrgID, challenge.ExpiresAt)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	challenge.ID = id
+	return nil
+}
+
+func (r *mysqlMFAChallengeRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error) {
+	query := "SELECT id, token_hash, user_id, org_id, attempts, expires_at, used_at FROM mfa_challenges WHERE token_hash = ?"
+	var challenge domain.MFAChallenge
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&challenge.ID, &challenge.TokenHash, &challenge.UserID, &challenge.OrgID,
+		&challenge.Attempts, &challenge.ExpiresAt, &usedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if usedAt.Valid {
+		challenge.UsedAt = &usedAt.Time
+	}
+	return &challenge, nil
+}
+
+func (r *mysqlMFAChallengeRepository) RecordAttempt(ctx context.Context, id int64, max int) error {
+	query := "UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = ? AND attempts < ? AND used_at IS NULL"
+	return execAffectingRow(ctx, r.db, query, id, max)
+}
+
+func (r *mysqlMFAChallengeRepository) MarkUsed(ctx context.Context, id int64) error {
+	query := "UPDATE mfa_challenges SET used_at = CURRENT_TIMESTAMP WHERE id = ? AND used_at IS NULL"
+	return execAffectingRow(ctx, r.db, query, id)
+}
+
+func (r *mysqlMFAChallengeRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM mfa_challenges WHERE expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
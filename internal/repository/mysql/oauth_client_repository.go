@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+type mysqlOAuthClientRepository struct {
+	db repository.DBTX
+}
+
+// NewOAuthClientRepository creates a new OAuthClientRepository
+func NewOAuthClientRepository(db repository.DBTX) repository.OAuthClientRepository {
+	return &mysqlOAuthClientRepository{db: db}
+}
+
+func (r *mysqlOAuthClientRepository) Create(ctx context.Context, client *domain.OAuthClient) error {
+	redirectURIs, err := json.Marshal(client.RedirectURIs)
+	if err != nil {
+		return err
+	}
+	var secretHash sql.NullString
+	if client.SecretHash != "" {
+		secretHash = sql.NullString{String: client.SecretHash, Valid: true}
+	}
+
+	query := "INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris) VALUES (?, ?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, client.ClientID, secretHash, client.Name, redirectURIs)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	client.ID = id
+	return nil
+}
+
+func (r *mysqlOAuthClientRepository) FindByClientID(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	query := "SELECT id, client_id, client_secret_hash, name, redirect_uris, created_at FROM oauth_clients WHERE client_id = ?"
+	return scanOAuthClient(r.db.QueryRowContext(ctx, query, clientID))
+}
+
+func (r *mysqlOAuthClientRepository) List(ctx context.Context) ([]domain.OAuthClient, error) {
+	query := "SELECT id, client_id, client_secret_hash, name, redirect_uris, created_at FROM oauth_clients ORDER BY id"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []domain.OAuthClient{}
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, *client)
+	}
+	return clients, rows.Err()
+}
+
+func (r *mysqlOAuthClientRepository) Delete(ctx context.Context, clientID string) error {
+	return execAffectingRow(ctx, r.db, "DELETE FROM oauth_clients WHERE client_id = ?", clientID)
+}
+
+func scanOAuthClient(row rowScanner) (*domain.OAuthClient, error) {
+	var client domain.OAuthClient
+	var secretHash sql.NullString
+	var redirectURIs []byte
+	err := row.Scan(&client.ID, &client.ClientID, &secretHash, &client.Name, &redirectURIs, &client.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	client.SecretHash = secretHash.String
+	if err := json.Unmarshal(redirectURIs, &client.RedirectURIs); err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
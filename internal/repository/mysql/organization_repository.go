@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+type mysqlOrganizationRepository struct {
+	db repository.DBTX
+}
+
+// NewOrganizationRepository creates a new OrganizationRepository
+func NewOrganizationRepository(db repository.DBTX) repository.OrganizationRepository {
+	return &mysqlOrganizationRepository{db: db}
+}
+
+func (r *mysqlOrganizationRepository) Create(ctx context.Context, org *domain.Organization) error {
+	query := "INSERT INTO organizations (name) VALUES (?)"
+	res, err := r.db.ExecContext(ctx, query, org.Name)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	org.ID = id
+	return nil
+}
+
+func (r *mysqlOrganizationRepository) FindByID(ctx context.Context, id int64) (*domain.Organization, error) {
+	query := "SELECT id, name, created_at FROM organizations WHERE id = ?"
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var org domain.Organization
+	err := row.Scan(&org.ID, &org.Name, &org.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *mysqlOrganizationRepository) AddMember(ctx context.Context, orgID, userID int64) error {
+	query := "INSERT IGNORE INTO organization_members (org_id, user_id) VALUES (?, ?)"
+	_, err := r.db.ExecContext(ctx, query, orgID, userID)
+	return err
+}
+
+func (r *mysqlOrganizationRepository) IsMember(ctx context.Context, orgID, userID int64) (bool, error) {
+	query := "SELECT 1 FROM organization_members WHERE org_id = ? AND user_id = ?"
+	var one int
+	err := r.db.QueryRowContext(ctx, query, orgID, userID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListForUser returns the user's organizations, oldest membership first
+func (r *mysqlOrganizationRepository) ListForUser(ctx context.Context, userID int64) ([]domain.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_at
+		FROM organizations o
+		JOIN organization_members om ON om.org_id = o.id
+		WHERE om.user_id = ?
+		ORDER BY om.created_at, o.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []domain.Organization
+	for rows.Next() {
+		var org domain.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
@@ -2,10 +2,14 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
 	"rbac/internal/domain"
 	"rbac/internal/repository"
 )
 
+// productColumns is the column list scanProduct expects
+const productColumns = "id, org_id, name, price, created_by_user, created_at"
+
 type mysqlProductRepository struct {
 	db repository.DBTX
 }
@@ -15,8 +19,8 @@ func NewProductRepository(db repository.DBTX) repository.ProductRepository {
 }
 
 func (r *mysqlProductRepository) Create(ctx context.Context, product *domain.Product) error {
-	query := "INSERT INTO products (name, price, created_by_user) VALUES (?, ?, ?)"
-	res, err := r.db.ExecContext(ctx, query, product.Name, product.Price, product.CreatedByUserID)
+	query := "INSERT INTO products (org_id, name, price, created_by_user) VALUES (?, ?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, product.OrgID, product.Name, product.Price, product.CreatedByUserID)
 	if err != nil {
 		return err
 	}
@@ -29,7 +33,101 @@ func (r *mysqlProductRepository) Create(ctx context.Context, product *domain.Pro
 }
 
 func (r *mysqlProductRepository) FindByID(ctx context.Context, id int64) (*domain.Product, error) {
-	// ... implementation ...
-	// (For brevity, you can implement this similar to FindByID in user repo)
-	return nil, nil
-}
\ No newline at end of file
+	query := "SELECT " + productColumns + " FROM products WHERE id = ?"
+	row := r.db.QueryRowContext(ctx, query, id)
+	return scanProduct(row)
+}
+
+func (r *mysqlProductRepository) List(ctx context.Context, orgID int64, filter domain.ProductFilter, afterID int64, limit int) ([]domain.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE org_id = ? AND id > ?"
+	args := []interface{}{orgID, afterID}
+	if filter.Name != "" {
+		query += " AND name LIKE ?"
+		args = append(args, "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.MinPrice != nil {
+		query += " AND price >= ?"
+		args = append(args, *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query += " AND price <= ?"
+		args = append(args, *filter.MaxPrice)
+	}
+	if filter.CreatedBy != nil {
+		query += " AND created_by_user = ?"
+		args = append(args, *filter.CreatedBy)
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *product)
+	}
+	return products, rows.Err()
+}
+
+func (r *mysqlProductRepository) Update(ctx context.Context, product *domain.Product) error {
+	query := "UPDATE products SET name = ?, price = ? WHERE id = ?"
+	res, err := r.db.ExecContext(ctx, query, product.Name, product.Price, product.ID)
+	if err != nil {
+		return err
+	}
+	// An update that changes nothing affects no rows; only a missing row is an error
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := r.FindByID(ctx, product.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *mysqlProductRepository) Delete(ctx context.Context, id int64) error {
+	return execAffectingRow(ctx, r.db, "DELETE FROM products WHERE id = ?", id)
+}
+
+func (r *mysqlProductRepository) FindOwner(ctx context.Context, id int64) (*domain.ResourceOwner, error) {
+	query := `
+		SELECT p.org_id, p.created_by_user, u.team_id
+		FROM products p
+		JOIN users u ON u.id = p.created_by_user
+		WHERE p.id = ?
+	`
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var owner domain.ResourceOwner
+	var teamID sql.NullInt64
+	if err := row.Scan(&owner.OrgID, &owner.OwnerID, &teamID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if teamID.Valid {
+		owner.TeamID = &teamID.Int64
+	}
+	return &owner, nil
+}
+
+func scanProduct(row rowScanner) (*domain.Product, error) {
+	var product domain.Product
+	err := row.Scan(&product.ID, &product.OrgID, &product.Name, &product.Price, &product.CreatedByUserID, &product.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &product, nil
+}
@@ -0,0 +1,75 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+type mysqlRefreshTokenRepository struct {
+	db repository.DBTX
+}
+
+// NewRefreshTokenRepository creates a new RefreshTokenRepository
+func NewRefreshTokenRepository(db repository.DBTX) repository.RefreshTokenRepository {
+	return &mysqlRefreshTokenRepository{db: db}
+}
+
+func (r *mysqlRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens (token_hash, family_id, user_id, org_id, expires_at, mfa) VALUES (?, ?, ?, ?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, token.TokenHash, token.FamilyID, token.UserID, token.OrgID, token.ExpiresAt, token.MFA)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+func (r *mysqlRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT id, token_hash, family_id, user_id, org_id, expires_at, used_at, revoked_at, mfa, created_at
+		FROM refresh_tokens
+		WHERE token_hash = ?
+	`
+	var token domain.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.TokenHash, &token.FamilyID, &token.UserID, &token.OrgID,
+		&token.ExpiresAt, &usedAt, &revokedAt, &token.MFA, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return &token, nil
+}
+
+func (r *mysqlRefreshTokenRepository) MarkUsed(ctx context.Context, id int64) error {
+	query := "UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL"
+	return execAffectingRow(ctx, r.db, query, id)
+}
+
+func (r *mysqlRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = ? AND revoked_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *mysqlRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = ? AND revoked_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -16,16 +16,212 @@ func NewRoleRepository(db repository.DBTX) repository.RoleRepository {
 }
 
 func (r *mysqlRoleRepository) FindByName(ctx context.Context, name string) (*domain.Role, error) {
-	query := "SELECT id, name FROM roles WHERE name = ?"
-	row := r.db.QueryRowContext(ctx, query, name)
+	query := "SELECT " + roleColumns + " FROM roles WHERE name = ?"
+	return scanRole(r.db.QueryRowContext(ctx, query, name))
+}
+
+func (r *mysqlRoleRepository) FindByID(ctx context.Context, id int64) (*domain.Role, error) {
+	query := "SELECT " + roleColumns + " FROM roles WHERE id = ?"
+	return scanRole(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *mysqlRoleRepository) List(ctx context.Context) ([]domain.Role, error) {
+	query := "SELECT " + roleColumns + " FROM roles ORDER BY name"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *mysqlRoleRepository) Create(ctx context.Context, role *domain.Role) error {
+	query := "INSERT INTO roles (name, parent_id, requires_mfa) VALUES (?, ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, role.Name, role.ParentID, role.RequiresMFA)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	role.ID = id
+	return nil
+}
+
+func (r *mysqlRoleRepository) Rename(ctx context.Context, roleID int64, name string) error {
+	query := "UPDATE roles SET name = ? WHERE id = ?"
+	if _, err := r.db.ExecContext(ctx, query, name, roleID); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *mysqlRoleRepository) SetRequiresMFA(ctx context.Context, roleID int64, requiresMFA bool) error {
+	query := "UPDATE roles SET requires_mfa = ? WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, requiresMFA, roleID)
+	return err
+}
+
+func (r *mysqlRoleRepository) Delete(ctx context.Context, roleID int64) error {
+	query := "DELETE FROM roles WHERE id = ?"
+	return execAffectingRow(ctx, r.db, query, roleID)
+}
+
+func (r *mysqlRoleRepository) SetParent(ctx context.Context, roleID int64, parentID *int64) error {
+	query := "UPDATE roles SET parent_id = ? WHERE id = ?"
+	res, err := r.db.ExecContext(ctx, query, parentID, roleID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// RowsAffected is 0 both for a missing row and for an unchanged parent
+		if _, err := r.FindByID(ctx, roleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *mysqlRoleRepository) FindPermissionByID(ctx context.Context, id int64) (*domain.Permission, error) {
+	query := "SELECT id, name, description FROM permissions WHERE id = ?"
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var perm domain.Permission
+	var description sql.NullString
+	err := row.Scan(&perm.ID, &perm.Name, &description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	perm.Description = description.String
+	return &perm, nil
+}
+
+func (r *mysqlRoleRepository) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	query := "SELECT id, name, description FROM permissions ORDER BY name"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []domain.Permission
+	for rows.Next() {
+		var perm domain.Permission
+		var description sql.NullString
+		if err := rows.Scan(&perm.ID, &perm.Name, &description); err != nil {
+			return nil, err
+		}
+		perm.Description = description.String
+		perms = append(perms, perm)
+	}
+	return perms, rows.Err()
+}
+
+func (r *mysqlRoleRepository) CreatePermission(ctx context.Context, perm *domain.Permission) error {
+	query := "INSERT INTO permissions (name, description) VALUES (?, ?)"
+	res, err := r.db.ExecContext(ctx, query, perm.Name, perm.Description)
+	if err != nil {
+		return translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	perm.ID = id
+	return nil
+}
+
+func (r *mysqlRoleRepository) UpdatePermission(ctx context.Context, perm *domain.Permission) error {
+	query := "UPDATE permissions SET name = ?, description = ? WHERE id = ?"
+	if _, err := r.db.ExecContext(ctx, query, perm.Name, perm.Description, perm.ID); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *mysqlRoleRepository) DeletePermission(ctx context.Context, id int64) error {
+	query := "DELETE FROM permissions WHERE id = ?"
+	return execAffectingRow(ctx, r.db, query, id)
+}
+
+func (r *mysqlRoleRepository) ListGrants(ctx context.Context, roleID int64) ([]domain.RoleGrant, error) {
+	query := `
+		SELECT p.id, p.name, rp.scope, rp.effect, rp.condition_expr
+		FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE rp.role_id = ?
+		ORDER BY p.name
+	`
+	rows, err := r.db.QueryContext(ctx, query, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	var grants []domain.RoleGrant
+	for rows.Next() {
+		var grant domain.RoleGrant
+		var condition sql.NullString
+		if err := rows.Scan(&grant.PermissionID, &grant.Permission, &grant.Scope, &grant.Effect, &condition); err != nil {
+			return nil, err
+		}
+		grant.Condition = condition.String
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+func (r *mysqlRoleRepository) GrantPermission(ctx context.Context, roleID int64, grant domain.RoleGrant) error {
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id, scope, effect, condition_expr)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE scope = VALUES(scope), effect = VALUES(effect), condition_expr = VALUES(condition_expr)
+	`
+	var condition sql.NullString
+	if grant.Condition != "" {
+		condition = sql.NullString{String: grant.Condition, Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx, query, roleID, grant.PermissionID, grant.Scope, grant.Effect, condition)
+	return err
+}
+
+func (r *mysqlRoleRepository) RevokePermission(ctx context.Context, roleID, permissionID int64) error {
+	query := "DELETE FROM role_permissions WHERE role_id = ? AND permission_id = ?"
+	return execAffectingRow(ctx, r.db, query, roleID, permissionID)
+}
+
+// roleColumns is the column list scanRole expects
+const roleColumns = "id, name, parent_id, requires_mfa"
+
+func scanRole(row rowScanner) (*domain.Role, error) {
 	var role domain.Role
-	err := row.Scan(&role.ID, &role.Name)
+	var parentID sql.NullInt64
+	err := row.Scan(&role.ID, &role.Name, &parentID, &role.RequiresMFA)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, repository.ErrNotFound
 		}
 		return nil, err
 	}
+	if parentID.Valid {
+		role.ParentID = &parentID.Int64
+	}
 	return &role, nil
-}
\ No newline at end of file
+}
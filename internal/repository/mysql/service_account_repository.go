@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+// serviceAccountColumns is the column list scanServiceAccount expects
+const serviceAccountColumns = `
+	sa.user_id, sa.org_id, u.username, sa.client_id, sa.client_secret_hash,
+	sa.description, sa.created_by, sa.created_at`
+
+type mysqlServiceAccountRepository struct {
+	db repository.DBTX
+}
+
+// NewServiceAccountRepository creates a new ServiceAccountRepository
+func NewServiceAccountRepository(db repository.DBTX) repository.ServiceAccountRepository {
+	return &mysqlServiceAccountRepository{db: db}
+}
+
+func (r *mysqlServiceAccountRepository) Create(ctx context.Context, account *domain.ServiceAccount) error {
+	query := `
+		INSERT INTO service_accounts (user_id, org_id, client_id, client_secret_hash, description, created_by)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?)
+	`
+	_, err := r.db.ExecContext(ctx, query, account.UserID, account.OrgID, account.ClientID,
+		account.SecretHash, account.Description, account.CreatedBy)
+	return translateError(err)
+}
+
+func (r *mysqlServiceAccountRepository) FindByClientID(ctx context.Context, clientID string) (*domain.ServiceAccount, error) {
+	query := "SELECT " + serviceAccountColumns + " FROM service_accounts sa JOIN users u ON u.id = sa.user_id WHERE sa.client_id = ?"
+	return scanServiceAccount(r.db.QueryRowContext(ctx, query, clientID))
+}
+
+func (r *mysqlServiceAccountRepository) FindByUserID(ctx context.Context, userID int64) (*domain.ServiceAccount, error) {
+	query := "SELECT " + serviceAccountColumns + " FROM service_accounts sa JOIN users u ON u.id = sa.user_id WHERE sa.user_id = ?"
+	return scanServiceAccount(r.db.QueryRowContext(ctx, query, userID))
+}
+
+func (r *mysqlServiceAccountRepository) ListByOrg(ctx context.Context, orgID int64) ([]domain.ServiceAccount, error) {
+	query := "SELECT " + serviceAccountColumns + " FROM service_accounts sa JOIN users u ON u.id = sa.user_id WHERE sa.org_id = ? ORDER BY u.username"
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []domain.ServiceAccount{}
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+	}
+	return accounts, rows.Err()
+}
+
+func (r *mysqlServiceAccountRepository) UpdateSecret(ctx context.Context, userID int64, secretHash string) error {
+	query := "UPDATE service_accounts SET client_secret_hash = ? WHERE user_id = ?"
+	return execAffectingRow(ctx, r.db, query, secretHash, userID)
+}
+
+func (r *mysqlServiceAccountRepository) Delete(ctx context.Context, userID int64) error {
+	// The service_accounts row cascades away with the user
+	query := "DELETE FROM users WHERE id = ? AND is_service_account = TRUE"
+	return execAffectingRow(ctx, r.db, query, userID)
+}
+
+func scanServiceAccount(row rowScanner) (*domain.ServiceAccount, error) {
+	var account domain.ServiceAccount
+	var description sql.NullString
+	var createdBy sql.NullInt64
+	err := row.Scan(&account.UserID, &account.OrgID, &account.Name, &account.ClientID, &account.SecretHash,
+		&description, &createdBy, &account.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	account.Description = description.String
+	if createdBy.Valid {
+		account.CreatedBy = &createdBy.Int64
+	}
+	return &account, nil
+}
@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"context"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+)
+
+type mysqlSigningKeyRepository struct {
+	db repository.DBTX
+}
+
+// NewSigningKeyRepository creates a new SigningKeyRepository
+func NewSigningKeyRepository(db repository.DBTX) repository.SigningKeyRepository {
+	return &mysqlSigningKeyRepository{db: db}
+}
+
+func (r *mysqlSigningKeyRepository) Create(ctx context.Context, key *domain.SigningKeyRecord) error {
+	query := "INSERT INTO signing_keys (kid, algorithm, sealed_private_key, created_at, expires_at) VALUES (?, ?, ?, ?, ?)"
+	_, err := r.db.ExecContext(ctx, query, key.KID, key.Algorithm, key.SealedPrivateKey, key.CreatedAt, key.ExpiresAt)
+	return translateError(err)
+}
+
+func (r *mysqlSigningKeyRepository) ListValid(ctx context.Context) ([]domain.SigningKeyRecord, error) {
+	query := `
+		SELECT kid, algorithm, sealed_private_key, created_at, expires_at
+		FROM signing_keys
+		WHERE expires_at > CURRENT_TIMESTAMP
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.SigningKeyRecord{}
+	for rows.Next() {
+		var k domain.SigningKeyRecord
+		if err := rows.Scan(&k.KID, &k.Algorithm, &k.SealedPrivateKey, &k.CreatedAt, &k.ExpiresAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *mysqlSigningKeyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM signing_keys WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
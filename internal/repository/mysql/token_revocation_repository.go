@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"context"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"time"
+)
+
+type mysqlTokenRevocationRepository struct {
+	db repository.DBTX
+}
+
+// NewTokenRevocationRepository creates a new TokenRevocationRepository
+func NewTokenRevocationRepository(db repository.DBTX) repository.TokenRevocationRepository {
+	return &mysqlTokenRevocationRepository{db: db}
+}
+
+// Revoke records a revocation; revoking a token twice is not an error
+func (r *mysqlTokenRevocationRepository) Revoke(ctx context.Context, token domain.RevokedToken) error {
+	query := "INSERT IGNORE INTO revoked_tokens (jti, user_id, expires_at) VALUES (?, ?, ?)"
+	_, err := r.db.ExecContext(ctx, query, token.JTI, token.UserID, token.ExpiresAt)
+	return err
+}
+
+func (r *mysqlTokenRevocationRepository) ListSince(ctx context.Context, since time.Time) ([]domain.RevokedToken, error) {
+	query := `
+		SELECT jti, user_id, expires_at
+		FROM revoked_tokens
+		WHERE created_at >= ? AND expires_at > CURRENT_TIMESTAMP
+	`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []domain.RevokedToken{}
+	for rows.Next() {
+		var t domain.RevokedToken
+		if err := rows.Scan(&t.JTI, &t.UserID, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *mysqlTokenRevocationRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -5,8 +5,13 @@ import (
 	"database/sql"
 	"rbac/internal/domain"
 	"rbac/internal/repository"
+	"strings"
+	"time"
 )
 
+// userColumns is the column list scanUser expects
+const userColumns = "id, username, email, email_verified_at, password_hash, team_id, is_service_account, is_active, deactivated_at, sessions_revoked_at, created_at"
+
 type mysqlUserRepository struct {
 	db repository.DBTX
 }
@@ -17,63 +22,301 @@ func NewUserRepository(db repository.DBTX) repository.UserRepository {
 }
 
 func (r *mysqlUserRepository) Create(ctx context.Context, user *domain.User) error {
-	query := "INSERT INTO users (username, email, password_hash) VALUES (?, ?, ?)"
-	res, err := r.db.ExecContext(ctx, query, user.Username, user.Email, user.PasswordHash)
+	// Service accounts have no email; store NULL so the unique index allows many
+	query := "INSERT INTO users (username, email, password_hash, is_service_account) VALUES (?, NULLIF(?, ''), ?, ?)"
+	res, err := r.db.ExecContext(ctx, query, user.Username, user.Email, user.PasswordHash, user.IsServiceAccount)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 	id, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
 	user.ID = id
+	user.IsActive = true // Column default
 	return nil
 }
 
 func (r *mysqlUserRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
-	query := "SELECT id, username, email, password_hash, created_at FROM users WHERE username = ?"
+	query := "SELECT " + userColumns + " FROM users WHERE username = ?"
 	row := r.db.QueryRowContext(ctx, query, username)
-	
-	var user domain.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, repository.ErrNotFound
-		}
-		return nil, err
-	}
-	return &user, nil
+	return scanUser(row)
+}
+
+func (r *mysqlUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = ?"
+	row := r.db.QueryRowContext(ctx, query, email)
+	return scanUser(row)
 }
 
 func (r *mysqlUserRepository) FindByID(ctx context.Context, id int64) (*domain.User, error) {
-	query := "SELECT id, username, email, password_hash, created_at FROM users WHERE id = ?"
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
 	row := r.db.QueryRowContext(ctx, query, id)
+	return scanUser(row)
+}
 
-	var user domain.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt)
+// Search matches the query as a substring of username or email, ordered by ID
+func (r *mysqlUserRepository) Search(ctx context.Context, orgID int64, search domain.UserSearch) ([]domain.User, int, error) {
+	where := "WHERE om.org_id = ?"
+	args := []interface{}{orgID}
+	if search.Query != "" {
+		pattern := "%" + escapeLike(search.Query) + "%"
+		where += " AND (u.username LIKE ? OR u.email LIKE ?)"
+		args = append(args, pattern, pattern)
+	}
+	from := " FROM users u JOIN organization_members om ON om.user_id = u.id "
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT u." + strings.ReplaceAll(userColumns, ", ", ", u.") + from + where + " ORDER BY u.id LIMIT ? OFFSET ?"
+	args = append(args, search.PageSize, (search.Page-1)*search.PageSize)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, repository.ErrNotFound
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
 		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+func (r *mysqlUserRepository) SetActive(ctx context.Context, userID int64, active bool) error {
+	query := "UPDATE users SET is_active = ?, deactivated_at = IF(?, NULL, CURRENT_TIMESTAMP) WHERE id = ?"
+	res, err := r.db.ExecContext(ctx, query, active, active, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := r.FindByID(ctx, userID)
+		return err
+	}
+	return nil
+}
+
+func (r *mysqlUserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	query := "UPDATE users SET password_hash = ? WHERE id = ?"
+	return execAffectingRow(ctx, r.db, query, passwordHash, userID)
+}
+
+func (r *mysqlUserRepository) UpdatePasswordIfUnchanged(ctx context.Context, userID int64, oldHash, newHash string) error {
+	query := "UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?"
+	return execAffectingRow(ctx, r.db, query, newHash, userID, oldHash)
+}
+
+func (r *mysqlUserRepository) MarkEmailVerified(ctx context.Context, userID int64, email string, at time.Time) error {
+	query := "UPDATE users SET email_verified_at = ? WHERE id = ? AND email = ?"
+	return execAffectingRow(ctx, r.db, query, at, userID, email)
+}
+
+func (r *mysqlUserRepository) AssignRole(ctx context.Context, userID, roleID, orgID int64, validFrom time.Time) error {
+	query := "INSERT INTO user_roles (user_id, role_id, org_id, valid_from) VALUES (?, ?, ?, ?)"
+	_, err := r.db.ExecContext(ctx, query, userID, roleID, orgID, validFrom)
+	return err
+}
+
+// RevokeSessions stores at truncated to whole seconds, the precision of iat;
+// MySQL would round it up otherwise
+func (r *mysqlUserRepository) RevokeSessions(ctx context.Context, userID int64, at time.Time) error {
+	query := "UPDATE users SET sessions_revoked_at = ? WHERE id = ?"
+	res, err := r.db.ExecContext(ctx, query, at.Truncate(time.Second), userID)
+	if err != nil {
+		return err
+	}
+	// Revoking twice in the same second changes nothing; only a missing user is an error
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := r.FindByID(ctx, userID)
+		return err
+	}
+	return nil
+}
+
+func (r *mysqlUserRepository) RevokeRole(ctx context.Context, userID, roleID, orgID int64) error {
+	query := "DELETE FROM user_roles WHERE user_id = ? AND role_id = ? AND org_id = ?"
+	return execAffectingRow(ctx, r.db, query, userID, roleID, orgID)
+}
+
+func (r *mysqlUserRepository) ListRoleAssignments(ctx context.Context, userID, orgID int64) ([]domain.RoleAssignment, error) {
+	query := `
+		SELECT ur.user_id, ur.role_id, ur.org_id, r.name, ur.valid_from, ur.valid_until
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = ? AND ur.org_id = ?
+		ORDER BY r.name
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, orgID)
+	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	defer rows.Close()
+
+	assignments := []domain.RoleAssignment{}
+	for rows.Next() {
+		var a domain.RoleAssignment
+		var validUntil sql.NullTime
+		if err := rows.Scan(&a.UserID, &a.RoleID, &a.OrgID, &a.RoleName, &a.ValidFrom, &validUntil); err != nil {
+			return nil, err
+		}
+		if validUntil.Valid {
+			a.ValidUntil = &validUntil.Time
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
 }
 
-func (r *mysqlUserRepository) AssignRole(ctx context.Context, userID, roleID int64) error {
-	query := "INSERT INTO user_roles (user_id, role_id) VALUES (?, ?)"
-	_, err := r.db.ExecContext(ctx, query, userID, roleID)
+// AssignTemporaryRole assigns a role for a validity window.
+// Re-assigning an existing role replaces its window, which is how access is extended.
+func (r *mysqlUserRepository) AssignTemporaryRole(ctx context.Context, a domain.RoleAssignment) error {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, org_id, valid_from, valid_until)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE valid_from = VALUES(valid_from), valid_until = VALUES(valid_until)
+	`
+	_, err := r.db.ExecContext(ctx, query, a.UserID, a.RoleID, a.OrgID, a.ValidFrom, a.ValidUntil)
 	return err
 }
 
-// GetUserPermissions is the core of our RBAC check
-func (r *mysqlUserRepository) GetUserPermissions(ctx context.Context, userID int64) ([]string, error) {
+func (r *mysqlUserRepository) ListExpiredRoles(ctx context.Context, now time.Time, limit int) ([]domain.RoleAssignment, error) {
 	query := `
-		SELECT DISTINCT p.name
+		SELECT user_id, role_id, org_id, valid_from, valid_until
+		FROM user_roles
+		WHERE valid_until IS NOT NULL AND valid_until <= ?
+		ORDER BY valid_until
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []domain.RoleAssignment
+	for rows.Next() {
+		var a domain.RoleAssignment
+		var validUntil sql.NullTime
+		if err := rows.Scan(&a.UserID, &a.RoleID, &a.OrgID, &a.ValidFrom, &validUntil); err != nil {
+			return nil, err
+		}
+		if validUntil.Valid {
+			a.ValidUntil = &validUntil.Time
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// DeleteExpiredRole removes an assignment only if it is still expired, so an
+// assignment extended after it was listed survives
+func (r *mysqlUserRepository) DeleteExpiredRole(ctx context.Context, a domain.RoleAssignment, now time.Time) (bool, error) {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = ? AND role_id = ? AND org_id = ?
+		  AND valid_until IS NOT NULL AND valid_until <= ?
+	`
+	res, err := r.db.ExecContext(ctx, query, a.UserID, a.RoleID, a.OrgID, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// effectiveRolesCTE resolves every role a user holds in an organization: roles
+// assigned directly, roles assigned to the user's groups, and the parents of
+// those roles up the hierarchy. Each row records how the role was reached.
+// Direct assignments outside their validity window are ignored, and their
+// valid_until is carried up to inherited roles. mfa is set when the path to
+// the role passes through a role that requires MFA.
+// UNION (not UNION ALL) drops rows already produced, which keeps the walk finite
+// even if a cycle slipped into roles.parent_id.
+// Validity is checked against the caller's now rather than the database clock,
+// so it agrees with ListExpiredRoles and NextValidityChange.
+// Parameters: userID, orgID, now, now, userID, orgID.
+const effectiveRolesCTE = `
+	WITH RECURSIVE effective_roles (role_id, source, via, valid_until, mfa) AS (
+		SELECT ur.role_id, CAST('direct' AS CHAR(16)), CAST(NULL AS CHAR(100)), ur.valid_until, r.requires_mfa
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = ? AND ur.org_id = ?
+		  AND ur.valid_from <= ?
+		  AND (ur.valid_until IS NULL OR ur.valid_until > ?)
+		UNION
+		SELECT gr.role_id, 'group', g.name, NULL, r.requires_mfa
+		FROM group_roles gr
+		JOIN roles r ON r.id = gr.role_id
+		JOIN user_groups g ON g.id = gr.group_id
+		JOIN user_group_members gm ON gm.group_id = g.id
+		WHERE gm.user_id = ? AND g.org_id = ?
+		UNION
+		SELECT r.parent_id, 'inherited', r.name, er.valid_until, er.mfa OR p.requires_mfa
+		FROM roles r
+		JOIN effective_roles er ON r.id = er.role_id
+		JOIN roles p ON p.id = r.parent_id
+	)
+`
+
+// GetUserPermissions is the core of our RBAC check.
+// It collects grants from every effective role (direct, group-derived and
+// inherited, see effectiveRolesCTE). Grants attached directly to the user are
+// appended with an empty role name. Only assignments in orgID are considered.
+// A role grant requires MFA only if every path to the role does.
+func (r *mysqlUserRepository) GetUserPermissions(ctx context.Context, userID, orgID int64, now time.Time) ([]domain.Grant, error) {
+	query := effectiveRolesCTE + `
+		SELECT p.name, rp.scope, rp.effect, r.name, rp.condition_expr, er.mfa
 		FROM permissions p
 		JOIN role_permissions rp ON p.id = rp.permission_id
-		JOIN user_roles ur ON rp.role_id = ur.role_id
-		WHERE ur.user_id = ?
+		JOIN (SELECT role_id, MIN(mfa) AS mfa FROM effective_roles GROUP BY role_id) er ON rp.role_id = er.role_id
+		JOIN roles r ON r.id = er.role_id
+		UNION
+		SELECT p.name, 'any', up.effect, NULL, NULL, FALSE
+		FROM permissions p
+		JOIN user_permissions up ON p.id = up.permission_id
+		WHERE up.user_id = ? AND up.org_id = ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, orgID, now, now, userID, orgID, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []domain.Grant
+	for rows.Next() {
+		var grant domain.Grant
+		var role, condition sql.NullString
+		if err := rows.Scan(&grant.Permission, &grant.Scope, &grant.Effect, &role, &condition, &grant.RequiresMFA); err != nil {
+			return nil, err
+		}
+		grant.Role = role.String
+		grant.Condition = condition.String
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+func (r *mysqlUserRepository) GetPlatformPermissions(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+		SELECT p.name
+		FROM permissions p
+		JOIN platform_permissions pp ON pp.permission_id = p.id
+		WHERE pp.user_id = ?
 	`
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -81,14 +324,103 @@ func (r *mysqlUserRepository) GetUserPermissions(ctx context.Context, userID int
 	}
 	defer rows.Close()
 
-	var permissions []string
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// NextValidityChange only looks at direct assignments: group roles have no
+// validity window
+func (r *mysqlUserRepository) NextValidityChange(ctx context.Context, userID, orgID int64, now time.Time) (*time.Time, error) {
+	query := `
+		SELECT MIN(t) FROM (
+			SELECT valid_from AS t FROM user_roles WHERE user_id = ? AND org_id = ? AND valid_from > ?
+			UNION ALL
+			SELECT valid_until FROM user_roles WHERE user_id = ? AND org_id = ? AND valid_until > ?
+		) changes
+	`
+	var next sql.NullTime
+	if err := r.db.QueryRowContext(ctx, query, userID, orgID, now, userID, orgID, now).Scan(&next); err != nil {
+		return nil, err
+	}
+	if !next.Valid {
+		return nil, nil
+	}
+	return &next.Time, nil
+}
+
+// GetEffectiveRoles lists every role the user holds in orgID, once per path
+// it was reached by (a role can be both direct and group-derived)
+func (r *mysqlUserRepository) GetEffectiveRoles(ctx context.Context, userID, orgID int64, now time.Time) ([]domain.EffectiveRole, error) {
+	query := effectiveRolesCTE + `
+		SELECT DISTINCT r.id, r.name, r.parent_id, r.requires_mfa, er.source, er.via, er.valid_until
+		FROM effective_roles er
+		JOIN roles r ON r.id = er.role_id
+		ORDER BY r.name, er.source, er.via
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, orgID, now, now, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []domain.EffectiveRole
 	for rows.Next() {
-		var permName string
-		if err := rows.Scan(&permName); err != nil {
+		var er domain.EffectiveRole
+		var parentID sql.NullInt64
+		var via sql.NullString
+		var validUntil sql.NullTime
+		if err := rows.Scan(&er.Role.ID, &er.Role.Name, &parentID, &er.Role.RequiresMFA, &er.Source, &via, &validUntil); err != nil {
 			return nil, err
 		}
-		permissions = append(permissions, permName)
+		if validUntil.Valid {
+			er.ValidUntil = &validUntil.Time
+		}
+		if parentID.Valid {
+			er.Role.ParentID = &parentID.Int64
+		}
+		er.Via = via.String
+		roles = append(roles, er)
+	}
+
+	return roles, rows.Err()
+}
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	var user domain.User
+	var teamID sql.NullInt64
+	var email sql.NullString
+	var emailVerifiedAt, deactivatedAt, sessionsRevokedAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Username, &email, &emailVerifiedAt, &user.PasswordHash, &teamID, &user.IsServiceAccount, &user.IsActive, &deactivatedAt, &sessionsRevokedAt, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	user.Email = email.String
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if teamID.Valid {
+		user.TeamID = &teamID.Int64
+	}
+	if deactivatedAt.Valid {
+		user.DeactivatedAt = &deactivatedAt.Time
 	}
+	if sessionsRevokedAt.Valid {
+		user.SessionsRevokedAt = &sessionsRevokedAt.Time
+	}
+	return &user, nil
+}
 
-	return permissions, nil
-}
\ No newline at end of file
+// escapeLike escapes the LIKE wildcards in a user-supplied search string
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"rbac/internal/domain"
+	"rbac/internal/mail"
+	"rbac/internal/policy"
+	"rbac/internal/repository"
+	"rbac/internal/utils"
+	"strings"
+	"time"
+)
+
+// ErrInvalidActionToken is returned for a password reset or email verification
+// token that is forged, expired, already used or no longer matches the account
+var ErrInvalidActionToken = errors.New("token is invalid, expired or already used")
+
+type accountService struct {
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	apiKeyRepo  repository.APIKeyRepository
+	actionRepo  repository.ActionTokenRepository
+	auditRepo   repository.AuditRepository
+	mailer      mail.Mailer
+	statusCache *UserStatusCache
+	keyCache    *APIKeyCache
+	throttle    *LoginThrottle
+	resetLimits PasswordResetLimits
+	passwords   *PasswordPolicy
+	keys        *KeyManager
+	baseURL     string
+	resetTTL    time.Duration
+	verifyTTL   time.Duration
+}
+
+// NewAccountService creates a new AccountService. baseURL is the external
+// address that links in emails point to.
+func NewAccountService(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, apiKeyRepo repository.APIKeyRepository, actionRepo repository.ActionTokenRepository, auditRepo repository.AuditRepository, mailer mail.Mailer, statusCache *UserStatusCache, keyCache *APIKeyCache, throttle *LoginThrottle, resetLimits PasswordResetLimits, passwords *PasswordPolicy, keys *KeyManager, baseURL string, resetTTL, verifyTTL time.Duration) AccountService {
+	return &accountService{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		apiKeyRepo:  apiKeyRepo,
+		actionRepo:  actionRepo,
+		auditRepo:   auditRepo,
+		mailer:      mailer,
+		statusCache: statusCache,
+		keyCache:    keyCache,
+		throttle:    throttle,
+		resetLimits: resetLimits,
+		passwords:   passwords,
+		keys:        keys,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		resetTTL:    resetTTL,
+		verifyTTL:   verifyTTL,
+	}
+}
+
+// PasswordResetLimits bound how often reset emails can be requested, so the
+// endpoint cannot be used to flood an inbox or send mail in bulk
+type PasswordResetLimits struct {
+	PerAddress *RateLimiter
+	PerIP      *RateLimiter
+}
+
+// RequestPasswordReset mails a reset token to the account registered with
+// email. It succeeds without sending anything for unknown addresses, so the
+// response does not reveal which addresses are registered. Requests over the
+// limits fail with ErrRateLimited, whether or not the address is registered.
+func (s *accountService) RequestPasswordReset(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return newValidationError("email is required")
+	}
+	if err := s.resetLimits.PerIP.Allow(ctx, policy.EnvironmentFrom(ctx).IP); err != nil {
+		return err
+	}
+	if err := s.resetLimits.PerAddress.Allow(ctx, utils.HashToken(strings.ToLower(email))); err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive || user.IsServiceAccount {
+		return nil
+	}
+
+	// Bound to the current password hash: any password change voids the token
+	token, err := s.issue(utils.PurposePasswordReset, user.ID, user.PasswordHash, s.resetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of %s. If it was you, send this token to POST %s/password/reset with your new password:\n\n%s\n\nThe token expires in %s. If you did not ask for this, ignore this email.",
+			user.Username, s.baseURL, token, s.resetTTL),
+	})
+}
+
+// ResetPassword sets a new password with a token from RequestPasswordReset and
+// logs the user out everywhere
+func (s *accountService) ResetPassword(ctx context.Context, req domain.ResetPasswordRequest) error {
+	if req.NewPassword == "" {
+		return newValidationError("new_password is required")
+	}
+	claims, user, err := s.verify(ctx, req.Token, utils.PurposePasswordReset, func(u *domain.User) string { return u.PasswordHash })
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return ErrInvalidActionToken // Deactivated after the token was sent
+	}
+	// Checked before the token is used up, so the user can try another password
+	if err := s.passwords.Check(ctx, req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	if err := s.consume(ctx, claims); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+	// The token arrived at the address, which proves the user owns it
+	if user.EmailVerifiedAt == nil {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID, user.Email, time.Now()); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+	// The lockout that may have prompted the reset no longer protects anything
+	if err := s.throttle.ResetAccount(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return s.auditRepo.Record(ctx, &domain.AuditEvent{
+		Type:        domain.AuditPasswordReset,
+		ActorUserID: &user.ID,
+		UserID:      &user.ID,
+		Details:     map[string]interface{}{"jti": claims.ID},
+	})
+}
+
+// ChangePassword replaces the password of a logged-in user who knows the
+// current one. Wrong current passwords count as failed logins. Every session,
+// including the caller's, is logged out.
+func (s *accountService) ChangePassword(ctx context.Context, userID int64, req domain.ChangePasswordRequest) error {
+	// A leaked API key must not be able to take over the account
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return ErrAPIKeyNotAllowed
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return newValidationError("current_password and new_password are required")
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsServiceAccount {
+		return newValidationError("service accounts have no password")
+	}
+
+	ip := policy.EnvironmentFrom(ctx).IP
+	if err := s.throttle.ReserveIP(ctx, ip); err != nil {
+		return err
+	}
+	if err := s.throttle.ReserveAccount(ctx, user.ID); err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.PasswordHash) {
+		if err := s.throttle.Failed(ctx, ip, user); err != nil {
+			return err
+		}
+		return newValidationError("current password is incorrect")
+	}
+	if err := s.throttle.Succeeded(ctx, ip, user.ID); err != nil {
+		return err
+	}
+
+	if err := s.passwords.Check(ctx, req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+	return s.auditRepo.Record(ctx, &domain.AuditEvent{
+		Type:        domain.AuditPasswordChanged,
+		ActorUserID: &user.ID,
+		UserID:      &user.ID,
+	})
+}
+
+// setPassword stores the hash of a new password, logs the user out everywhere
+// and deletes their API keys, since whoever knew the old password may still
+// hold sessions or have created keys
+func (s *accountService) setPassword(ctx context.Context, user *domain.User, password string) error {
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.RevokeSessions(ctx, user.ID, time.Now()); err != nil {
+		return err
+	}
+	s.statusCache.Invalidate(user.ID)
+	if err := s.refreshRepo.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+	return deleteAPIKeys(ctx, s.apiKeyRepo, s.keyCache, user.ID)
+}
+
+// SendVerificationEmail mails a link that verifies the user's current address
+func (s *accountService) SendVerificationEmail(ctx context.Context, userID int64) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return newValidationError("account has no email address")
+	}
+	if user.EmailVerifiedAt != nil {
+		return newValidationError("email address is already verified")
+	}
+
+	// Bound to the address: changing it voids the token
+	token, err := s.issue(utils.PurposeEmailVerification, user.ID, user.Email, s.verifyTTL)
+	if err != nil {
+		return err
+	}
+	link := s.baseURL + "/email/verify?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Confirm that %s is the address of %s by opening this link:\n\n%s\n\nThe link expires in %s.",
+			user.Email, user.Username, link, s.verifyTTL),
+	})
+}
+
+// VerifyEmail marks the address a token from SendVerificationEmail was sent to as verified
+func (s *accountService) VerifyEmail(ctx context.Context, token string) error {
+	claims, user, err := s.verify(ctx, token, utils.PurposeEmailVerification, func(u *domain.User) string { return u.Email })
+	if err != nil {
+		return err
+	}
+	if err := s.consume(ctx, claims); err != nil {
+		return err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID, user.Email, time.Now()); err == repository.ErrNotFound {
+		return ErrInvalidActionToken // The address changed meanwhile
+	} else if err != nil {
+		return err
+	}
+
+	return s.auditRepo.Record(ctx, &domain.AuditEvent{
+		Type:        domain.AuditEmailVerified,
+		ActorUserID: &user.ID,
+		UserID:      &user.ID,
+		Details:     map[string]interface{}{"email": user.Email, "jti": claims.ID},
+	})
+}
+
+// issue signs an action token for userID bound to state
+func (s *accountService) issue(purpose string, userID int64, state string, ttl time.Duration) (string, error) {
+	key, err := s.keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	return utils.GenerateActionToken(purpose, userID, utils.ActionBinding(state), key, ttl)
+}
+
+// verify checks an action token against the current state of its user, as
+// returned by state. The token stays usable until consume.
+func (s *accountService) verify(ctx context.Context, token, purpose string, state func(*domain.User) string) (*utils.ActionClaims, *domain.User, error) {
+	claims, err := utils.ValidateActionToken(token, purpose, s.keys.Lookup)
+	if err != nil {
+		return nil, nil, ErrInvalidActionToken
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, nil, ErrInvalidActionToken
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, nil, ErrInvalidActionToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.IsServiceAccount || utils.ActionBinding(state(user)) != claims.Binding {
+		return nil, nil, ErrInvalidActionToken
+	}
+	return claims, user, nil
+}
+
+// consume uses up a verified action token
+func (s *accountService) consume(ctx context.Context, claims *utils.ActionClaims) error {
+	if err := s.actionRepo.MarkUsed(ctx, claims.ID, claims.ExpiresAt.Time); err == repository.ErrDuplicate {
+		return ErrInvalidActionToken
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"rbac/internal/domain"
+	"sync"
+	"time"
+)
+
+// APIKeyCache remembers API keys by the hash of the presented key, so
+// AuthMiddleware does not need a database round trip on every request.
+// APIKeyService invalidates a user's keys when one is deleted and when they are
+// all deleted on session revocation or a password change, so both take effect
+// immediately on this instance and within the TTL elsewhere. Organization
+// membership is not cached; Authenticate checks it on every request.
+//
+// A nil *APIKeyCache is valid and caches nothing.
+type APIKeyCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]apiKeyEntry
+	// generation changes on every invalidation, as in PermissionCache
+	generation uint64
+}
+
+type apiKeyEntry struct {
+	key       domain.APIKey
+	expiresAt time.Time
+}
+
+// NewAPIKeyCache creates a cache holding at most maxEntries keys for ttl each.
+// It returns nil (caching disabled) if ttl or maxEntries is not positive.
+func NewAPIKeyCache(ttl time.Duration, maxEntries int) *APIKeyCache {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &APIKeyCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]apiKeyEntry),
+	}
+}
+
+// Get returns a copy of the cached key and the current generation, which must
+// be passed back to Set after a miss.
+func (c *APIKeyCache) Get(keyHash string) (*domain.APIKey, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[keyHash]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, c.generation, false
+	}
+	key := entry.key
+	return &key, c.generation, true
+}
+
+// Set stores a key loaded at generation. It is a no-op if the cache was
+// invalidated since then.
+func (c *APIKeyCache) Set(key *domain.APIKey, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if _, ok := c.entries[key.KeyHash]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictExpired()
+		if len(c.entries) >= c.maxEntries {
+			// Entries are cheap to reload; start over rather than track recency
+			c.entries = make(map[string]apiKeyEntry)
+		}
+	}
+	c.entries[key.KeyHash] = apiKeyEntry{key: *key, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Touched records that last_used_at was written for a cached key
+func (c *APIKeyCache) Touched(keyHash string, at time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[keyHash]; ok {
+		entry.key.LastUsedAt = &at
+		c.entries[keyHash] = entry
+	}
+}
+
+// InvalidateUser drops every cached key of a user
+func (c *APIKeyCache) InvalidateUser(userID int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for hash, entry := range c.entries {
+		if entry.key.UserID == userID {
+			delete(c.entries, hash)
+		}
+	}
+}
+
+func (c *APIKeyCache) evictExpired() {
+	now := time.Now()
+	for hash, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, hash)
+		}
+	}
+}
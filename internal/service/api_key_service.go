@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"rbac/internal/utils"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidAPIKey is returned for an unknown or expired API key
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotAllowed is returned when a request authenticated with an API
+	// key tries to manage API keys or obtain tokens, which would let a
+	// restricted key escape its limits
+	ErrAPIKeyNotAllowed = errors.New("not allowed when authenticated with an API key")
+)
+
+const (
+	maxAPIKeyNameLength = 100
+	// apiKeyDisplayLength is how much of a key is kept in clear to identify it
+	apiKeyDisplayLength = len(domain.APIKeyPrefix) + 8
+	// lastUsedResolution bounds how often last_used_at is written for a busy key
+	lastUsedResolution = time.Minute
+)
+
+type apiKeyCtxKey struct{}
+
+// WithAPIKey records that the request was authenticated with key
+func WithAPIKey(ctx context.Context, key *domain.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey{}, key)
+}
+
+// APIKeyFromContext returns the API key set by WithAPIKey
+func APIKeyFromContext(ctx context.Context) (*domain.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(*domain.APIKey)
+	return key, ok && key != nil
+}
+
+// apiKeyAllows reports whether the request's API key, if any, may exercise
+// permission for userID. Checks about other users are not limited by the key.
+func apiKeyAllows(ctx context.Context, userID int64, permission string) bool {
+	key, ok := APIKeyFromContext(ctx)
+	if !ok || key.UserID != userID || len(key.Permissions) == 0 {
+		return true
+	}
+	for _, p := range key.Permissions {
+		if matchPermission(p, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+type apiKeyService struct {
+	keyRepo     repository.APIKeyRepository
+	userRepo    repository.UserRepository
+	orgRepo     repository.OrganizationRepository
+	rbacSvc     RBACService
+	cache       *APIKeyCache
+	statusCache *UserStatusCache
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(keyRepo repository.APIKeyRepository, userRepo repository.UserRepository, orgRepo repository.OrganizationRepository, rbacSvc RBACService, cache *APIKeyCache, statusCache *UserStatusCache) APIKeyService {
+	return &apiKeyService{
+		keyRepo:     keyRepo,
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		rbacSvc:     rbacSvc,
+		cache:       cache,
+		statusCache: statusCache,
+	}
+}
+
+// Create issues a key acting as userID in the active organization. Listed
+// permissions must all be held by the user now; the key is only returned here.
+func (s *apiKeyService) Create(ctx context.Context, userID int64, req domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error) {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return nil, ErrAPIKeyNotAllowed
+	}
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, newValidationError("API key name is required")
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return nil, newValidationError(fmt.Sprintf("API key name must be at most %d characters", maxAPIKeyNameLength))
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, newValidationError("expires_at must be in the future")
+	}
+	for _, permission := range req.Permissions {
+		if !permissionNamePattern.MatchString(permission) {
+			return nil, newValidationError(fmt.Sprintf("invalid permission %q", permission))
+		}
+		decision, err := s.rbacSvc.CheckPermission(ctx, userID, permission)
+		if err != nil {
+			return nil, err
+		}
+		if !decision.Allowed {
+			return nil, newValidationError(fmt.Sprintf("you do not hold permission %q", permission))
+		}
+	}
+
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := domain.APIKeyPrefix + secret
+	key := &domain.APIKey{
+		UserID:      userID,
+		OrgID:       orgID,
+		Name:        name,
+		Prefix:      raw[:apiKeyDisplayLength],
+		KeyHash:     utils.HashToken(raw),
+		Permissions: req.Permissions,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	return &domain.CreatedAPIKey{APIKey: *key, Key: raw}, nil
+}
+
+// List returns the user's keys, without the keys themselves
+func (s *apiKeyService) List(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return nil, ErrAPIKeyNotAllowed
+	}
+	return s.keyRepo.ListByUser(ctx, userID)
+}
+
+// Delete revokes one of the user's keys
+func (s *apiKeyService) Delete(ctx context.Context, userID, keyID int64) error {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return ErrAPIKeyNotAllowed
+	}
+	if err := s.keyRepo.Delete(ctx, keyID, userID); err != nil {
+		return err
+	}
+	s.cache.InvalidateUser(userID)
+	return nil
+}
+
+// Authenticate resolves a presented key, checking its expiry and that its
+// owner is still active and still a member of the key's organization
+func (s *apiKeyService) Authenticate(ctx context.Context, raw string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(raw, domain.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	keyHash := utils.HashToken(raw)
+	key, generation, ok := s.cache.Get(keyHash)
+	if !ok {
+		var err error
+		key, err = s.keyRepo.FindByHash(ctx, keyHash)
+		if err == repository.ErrNotFound {
+			return nil, ErrInvalidAPIKey
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Set(key, generation)
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+	// Checked on every request, cached key or not: memberships are removed
+	// directly in the database, where no service can invalidate the cache
+	member, err := s.orgRepo.IsMember(ctx, key.OrgID, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrInvalidAPIKey
+	}
+	if _, err := loadUserStatus(ctx, s.userRepo, s.statusCache, key.UserID); err != nil {
+		return nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.keyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+		s.cache.Touched(keyHash, now)
+	}
+	return key, nil
+}
+
+// deleteAPIKeys deletes every key of a user, for when their sessions are
+// revoked or their password changes: a key must not outlive the credentials
+// that created it
+func deleteAPIKeys(ctx context.Context, keyRepo repository.APIKeyRepository, cache *APIKeyCache, userID int64) error {
+	if err := keyRepo.DeleteAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	cache.InvalidateUser(userID)
+	return nil
+}
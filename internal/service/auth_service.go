@@ -3,26 +3,63 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"rbac/internal/domain"
+	"rbac/internal/policy"
 	"rbac/internal/repository"
 	"rbac/internal/utils"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown username or a wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrAccountDeactivated is returned when a deactivated user logs in or presents a token
+	ErrAccountDeactivated = errors.New("account is deactivated")
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrTokenRevoked is returned for an access token revoked by logout or by an admin
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRefreshTokenReused is returned when a refresh token is exchanged twice;
+	// its whole family has been revoked and the user must log in again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected; session revoked")
 )
 
 // authService is the implementation of AuthService
 type authService struct {
-	userRepo      repository.UserRepository
-	roleRepo      repository.RoleRepository
-	jwtSecret     string
-	jwtExpiration int64
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	orgRepo     repository.OrganizationRepository
+	refreshRepo repository.RefreshTokenRepository
+	auditRepo   repository.AuditRepository
+	tx          repository.Transactor
+	mfaSvc      MFAService
+	statusCache *UserStatusCache
+	revocations *TokenRevocationList
+	throttle    *LoginThrottle
+	passwords   *PasswordPolicy
+	keys        *KeyManager
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
 }
 
 // NewAuthService creates a new AuthService
-func NewAuthService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, jwtSecret string, jwtExp int64) AuthService {
+func NewAuthService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, orgRepo repository.OrganizationRepository, refreshRepo repository.RefreshTokenRepository, auditRepo repository.AuditRepository, tx repository.Transactor, mfaSvc MFAService, statusCache *UserStatusCache, revocations *TokenRevocationList, throttle *LoginThrottle, passwords *PasswordPolicy, keys *KeyManager, accessTTL, refreshTTL time.Duration) AuthService {
 	return &authService{
-		userRepo:      userRepo,
-		roleRepo:      roleRepo,
-		jwtSecret:     jwtSecret,
-		jwtExpiration: jwtExp,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		orgRepo:     orgRepo,
+		refreshRepo: refreshRepo,
+		auditRepo:   auditRepo,
+		tx:          tx,
+		mfaSvc:      mfaSvc,
+		statusCache: statusCache,
+		revocations: revocations,
+		throttle:    throttle,
+		passwords:   passwords,
+		keys:        keys,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
 	}
 }
 
@@ -36,8 +73,12 @@ func (s *authService) Register(ctx context.Context, req domain.RegisterRequest)
 		return nil, err
 	}
 
+	if err := s.passwords.Check(ctx, req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Hash password
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +113,12 @@ func (s *authService) Register(ctx context.Context, req domain.RegisterRequest)
 		return nil, err
 	}
 	
-	if err := s.userRepo.AssignRole(ctx, user.ID, role.ID); err != nil {
+	// New users join the default organization with the "user" role there
+	if err := s.orgRepo.AddMember(ctx, domain.DefaultOrgID, user.ID); err != nil {
+		return nil, errors.New("failed to join default organization")
+	}
+
+	if err := s.userRepo.AssignRole(ctx, user.ID, role.ID, domain.DefaultOrgID, time.Now()); err != nil {
 		// Log this, but registration was successful
 		// log.Printf("Failed to assign default role to user %d: %v", user.ID, err)
 		return nil, errors.New("failed to assign default role")
@@ -83,25 +129,342 @@ func (s *authService) Register(ctx context.Context, req domain.RegisterRequest)
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, req domain.LoginRequest) (string, error) {
-	user, err := s.userRepo.FindByUsername(ctx, req.Username)
+func (s *authService) Login(ctx context.Context, req domain.LoginRequest) (*domain.LoginResponse, error) {
+	user, err := s.VerifyCredentials(ctx, req.Username, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	orgID, err := resolveLoginOrg(ctx, s.orgRepo, user.ID, req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users enrolled in MFA get a challenge to complete with CompleteMFALogin
+	enrolled, err := s.mfaSvc.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrolled {
+		token, err := s.mfaSvc.StartChallenge(ctx, user.ID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LoginResponse{MFARequired: true, MFAToken: token}, nil
+	}
+
+	// Each login starts a new refresh token family
+	return s.issueTokens(ctx, user.ID, orgID, "", false)
+}
+
+// CompleteMFALogin exchanges the challenge from Login and a TOTP or recovery
+// code for tokens
+func (s *authService) CompleteMFALogin(ctx context.Context, req domain.MFALoginRequest) (*domain.LoginResponse, error) {
+	challenge, err := s.mfaSvc.CompleteChallenge(ctx, req.MFAToken, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	// The account may have been deactivated since the password step
+	if _, err := loadUserStatus(ctx, s.userRepo, s.statusCache, challenge.UserID); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, challenge.UserID, challenge.OrgID, "", true)
+}
+
+// loginFailed reports a failed password check to the throttle and returns the error to report
+func (s *authService) loginFailed(ctx context.Context, ip string, user *domain.User) error {
+	if err := s.throttle.Failed(ctx, ip, user); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// VerifyCredentials checks a username and password and returns the active user.
+// Attempts from a client IP (see policy.WithEnvironment) or against an account
+// that failed too often are refused with a *LoginThrottledError.
+func (s *authService) VerifyCredentials(ctx context.Context, username, password string) (*domain.User, error) {
+	ip := policy.EnvironmentFrom(ctx).IP
+	if err := s.throttle.ReserveIP(ctx, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
 		if err == repository.ErrNotFound {
-			return "", errors.New("invalid username or password")
+			return nil, s.loginFailed(ctx, ip, nil)
 		}
-		return "", err
+		return nil, err
+	}
+
+	// Service accounts use the client_credentials grant, never a password
+	if user.IsServiceAccount {
+		return nil, s.loginFailed(ctx, ip, nil)
+	}
+
+	// Reserved before the password: a refused attempt costs no hash comparison.
+	// It still counts against the IP.
+	if err := s.throttle.ReserveAccount(ctx, user.ID); err != nil {
+		return nil, err
 	}
 
 	// Check password
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-		return "", errors.New("invalid username or password")
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		return nil, s.loginFailed(ctx, ip, user)
+	}
+	if err := s.throttle.Succeeded(ctx, ip, user.ID); err != nil {
+		return nil, err
+	}
+	// Checked after the password so the response does not reveal account state
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
 	}
 
-	// Generate JWT
-	token, err := utils.GenerateToken(user.ID, s.jwtSecret, s.jwtExpiration)
+	// The plain password is only at hand now: upgrade a hash that is weaker
+	// than the current policy. Failing to is no reason to refuse the login.
+	// The update only applies to the hash that was checked, so it cannot undo a
+	// password reset or change that landed in the meantime.
+	if s.passwords.NeedsRehash(user.PasswordHash) {
+		if hash, err := s.passwords.Hash(password); err != nil {
+			log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		} else if err := s.userRepo.UpdatePasswordIfUnchanged(ctx, user.ID, user.PasswordHash, hash); err == repository.ErrNotFound {
+			log.Printf("Skipped rehashing password of user %d: it was changed meanwhile", user.ID)
+		} else if err != nil {
+			log.Printf("Failed to store rehashed password of user %d: %v", user.ID, err)
+		} else {
+			user.PasswordHash = hash
+		}
+	}
+	return user, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token in the same family. Each refresh token can be exchanged once; presenting
+// it again revokes the family, logging out both the thief and the victim.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*domain.LoginResponse, error) {
+	stored, err := s.refreshRepo.FindByHash(ctx, utils.HashToken(refreshToken))
+	if err == repository.ErrNotFound {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
 	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	member, err := s.orgRepo.IsMember(ctx, stored.OrgID, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrNotOrgMember
+	}
+
+	// The old token is only used up if its successor is stored, so a failure
+	// here leaves the session as it was
+	var resp *domain.LoginResponse
+	reused := false
+	err = s.tx.WithinTx(ctx, func(tx repository.TxRepositories) error {
+		// A concurrent exchange of the same token loses here and counts as reuse
+		if err := tx.RefreshTokens.MarkUsed(ctx, stored.ID); err == repository.ErrNotFound {
+			reused = true
+			return err
+		} else if err != nil {
+			return err
+		}
+		var err error
+		resp, err = s.issueTokensIn(ctx, tx.RefreshTokens, stored.UserID, stored.OrgID, stored.FamilyID, stored.MFA)
+		return err
+	})
+	if reused {
+		// Outside the transaction, which has rolled back
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// revokeReusedFamily revokes every token descended from the same login and
+// records the reuse, then returns ErrRefreshTokenReused
+func (s *authService) revokeReusedFamily(ctx context.Context, stored *domain.RefreshToken) error {
+	if err := s.refreshRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	event := &domain.AuditEvent{
+		Type:    domain.AuditRefreshReuse,
+		UserID:  &stored.UserID,
+		OrgID:   &stored.OrgID,
+		Details: map[string]interface{}{"family_id": stored.FamilyID},
+	}
+	if err := s.auditRepo.Record(ctx, event); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens creates an access token and a refresh token in familyID,
+// or in a new family if familyID is empty. mfa records whether the login
+// completed multi-factor authentication.
+func (s *authService) issueTokens(ctx context.Context, userID, orgID int64, familyID string, mfa bool) (*domain.LoginResponse, error) {
+	return s.issueTokensIn(ctx, s.refreshRepo, userID, orgID, familyID, mfa)
+}
+
+// issueTokensIn is issueTokens storing the refresh token with refreshRepo,
+// which may be bound to a transaction
+func (s *authService) issueTokensIn(ctx context.Context, refreshRepo repository.RefreshTokenRepository, userID, orgID int64, familyID string, mfa bool) (*domain.LoginResponse, error) {
+	key, err := s.keys.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.GenerateToken(userID, orgID, utils.SessionAMR(mfa), key, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if familyID == "" {
+		if familyID, err = utils.RandomID(); err != nil {
+			return nil, err
+		}
+	}
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	stored := &domain.RefreshToken{
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    userID,
+		OrgID:     orgID,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+		MFA:       mfa,
+	}
+	if err := refreshRepo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+	}, nil
+}
+
+
+// Authenticate validates a token and checks that it has not been revoked and
+// that its user is still active. Both checks are served from memory.
+func (s *authService) Authenticate(ctx context.Context, tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateToken(tokenString, s.keys.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSession(ctx, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// AuthenticateClient is Authenticate for access tokens issued to OIDC clients,
+// which are only accepted by the routes serving those clients
+func (s *authService) AuthenticateClient(ctx context.Context, tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateClientToken(tokenString, s.keys.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSession(ctx, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkSession rejects a validated token that was revoked, or whose user was
+// deactivated or had their sessions revoked since it was issued
+func (s *authService) checkSession(ctx context.Context, claims *utils.Claims) error {
+	// Tokens issued before jti existed cannot be revoked individually
+	if claims.ID == "" {
+		return ErrTokenRevoked
+	}
+	if s.revocations.IsRevoked(claims.ID) {
+		return ErrTokenRevoked
+	}
+
+	status, err := loadUserStatus(ctx, s.userRepo, s.statusCache, claims.UserID)
+	if err != nil {
+		return err
+	}
+	// iat has one-second precision, so a token issued in the same second as the
+	// revocation is rejected too
+	if status.SessionsRevokedAt != nil && (claims.IssuedAt == nil || !claims.IssuedAt.After(*status.SessionsRevokedAt)) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// JWKS returns the public keys that verify access tokens
+func (s *authService) JWKS() utils.JWKS {
+	return s.keys.JWKS()
+}
+
+// Logout revokes the presented access token and, if given, the refresh token
+// family it was issued with
+func (s *authService) Logout(ctx context.Context, claims *utils.Claims, refreshToken string) error {
+	// Revoking an empty jti would revoke every token without one
+	if claims.ID != "" {
+		revoked := domain.RevokedToken{JTI: claims.ID, UserID: claims.UserID, ExpiresAt: time.Now().Add(s.accessTTL)}
+		if claims.ExpiresAt != nil {
+			revoked.ExpiresAt = claims.ExpiresAt.Time
+		}
+		if err := s.revocations.Revoke(ctx, revoked); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.refreshRepo.FindByHash(ctx, utils.HashToken(refreshToken))
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Do not let one user log out another user's session
+	if stored.UserID != claims.UserID {
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(ctx, stored.FamilyID)
+}
+
+// SwitchOrganization issues new tokens with orgID as the active organization.
+// The refresh token starts a new family bound to orgID.
+func (s *authService) SwitchOrganization(ctx context.Context, userID, orgID int64) (*domain.LoginResponse, error) {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return nil, ErrAPIKeyNotAllowed
+	}
+	member, err := s.orgRepo.IsMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrNotOrgMember
+	}
+
+	return s.issueTokens(ctx, userID, orgID, "", mfaVerified(ctx, userID))
+}
 
-	return token, nil
+// ListOrganizations returns the organizations a user can switch into
+func (s *authService) ListOrganizations(ctx context.Context, userID int64) ([]domain.Organization, error) {
+	return s.orgRepo.ListForUser(ctx, userID)
 }
\ No newline at end of file
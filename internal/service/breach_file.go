@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HashPrefixFile is a BreachedPasswordSource backed by a local copy of a
+// breached password corpus such as Have I Been Pwned's Pwned Passwords: a text
+// file of uppercase SHA-1 hashes with counts ("HASH:COUNT"), one per line and
+// sorted by hash. Ranges are found by binary search, so the file is never
+// loaded into memory.
+type HashPrefixFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenHashPrefixFile opens the corpus at path
+func OpenHashPrefixFile(path string) (*HashPrefixFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &HashPrefixFile{file: file, size: info.Size()}, nil
+}
+
+// Close closes the underlying file
+func (f *HashPrefixFile) Close() error {
+	return f.file.Close()
+}
+
+// Range returns the hashes starting with prefix
+func (f *HashPrefixFile) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	prefix = strings.ToUpper(prefix)
+
+	// Find the first line whose hash sorts at or after prefix
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		hash, err := f.hashAt(mid)
+		if err != nil {
+			return nil, err
+		}
+		if hash != "" && hash < prefix {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	start, err := f.lineStart(lo)
+	if err != nil {
+		return nil, err
+	}
+	suffixes := make(map[string]int)
+	scanner := bufio.NewScanner(io.NewSectionReader(f.file, start, f.size-start))
+	for scanner.Scan() {
+		hash, count := parseHashLine(scanner.Text())
+		if !strings.HasPrefix(hash, prefix) {
+			break
+		}
+		suffixes[hash[len(prefix):]] = count
+	}
+	return suffixes, scanner.Err()
+}
+
+// hashAt returns the hash on the first line starting at or after offset, or
+// "" at the end of the file
+func (f *HashPrefixFile) hashAt(offset int64) (string, error) {
+	start, err := f.lineStart(offset)
+	if err != nil || start >= f.size {
+		return "", err
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f.file, start, f.size-start))
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	hash, _ := parseHashLine(line)
+	return hash, nil
+}
+
+// lineStart returns the offset of the first line starting at or after offset
+func (f *HashPrefixFile) lineStart(offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	// The line starts right after the newline at or after offset-1
+	reader := bufio.NewReader(io.NewSectionReader(f.file, offset-1, f.size-offset+1))
+	skipped, err := reader.ReadString('\n')
+	if err == io.EOF {
+		return f.size, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return offset - 1 + int64(len(skipped)), nil
+}
+
+// parseHashLine splits a "HASH:COUNT" line
+func parseHashLine(line string) (string, int) {
+	line = strings.TrimSpace(line)
+	hash, countText, _ := strings.Cut(line, ":")
+	count, err := strconv.Atoi(countText)
+	if err != nil {
+		count = 1 // Listed without a count
+	}
+	return strings.ToUpper(hash), count
+}
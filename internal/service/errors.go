@@ -0,0 +1,49 @@
+package service
+
+import (
+	"rbac/internal/domain"
+	"strings"
+)
+
+// ValidationError is returned when a request is well-formed but its content is
+// not acceptable. The message is safe to show to the caller.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func newValidationError(message string) error {
+	return &ValidationError{Message: message}
+}
+
+// OAuthError is an OAuth2/OIDC protocol error. Code is one of the error codes
+// of RFC 6749 section 5.2 (or 4.1.2.1 for authorization requests).
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) error {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// PasswordPolicyError is returned when a new password breaks the password
+// policy. Every violation is listed, so the user can fix them all at once.
+type PasswordPolicyError struct {
+	Violations []domain.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
@@ -3,21 +3,132 @@ package service
 import (
 	"context"
 	"rbac/internal/domain"
+	"rbac/internal/utils"
 )
 
 // AuthService handles user registration and login
 type AuthService interface {
 	Register(ctx context.Context, req domain.RegisterRequest) (*domain.User, error)
-	Login(ctx context.Context, req domain.LoginRequest) (string, error)
+	Login(ctx context.Context, req domain.LoginRequest) (*domain.LoginResponse, error)
+	CompleteMFALogin(ctx context.Context, req domain.MFALoginRequest) (*domain.LoginResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*domain.LoginResponse, error)
+	VerifyCredentials(ctx context.Context, username, password string) (*domain.User, error)
+	Authenticate(ctx context.Context, tokenString string) (*utils.Claims, error)
+	AuthenticateClient(ctx context.Context, tokenString string) (*utils.Claims, error)
+	Logout(ctx context.Context, claims *utils.Claims, refreshToken string) error
+	JWKS() utils.JWKS
+	SwitchOrganization(ctx context.Context, userID, orgID int64) (*domain.LoginResponse, error)
+	ListOrganizations(ctx context.Context, userID int64) ([]domain.Organization, error)
 }
 
 // RBACService handles permission checks
 type RBACService interface {
-	CheckPermission(ctx context.Context, userID int64, requiredPermission string) (bool, error)
+	CheckPermission(ctx context.Context, userID int64, requiredPermission string) (domain.Decision, error)
+	CheckPermissions(ctx context.Context, userID int64, permissions []string) (map[string]bool, error)
+	CheckResourcePermission(ctx context.Context, userID int64, requiredPermission, resourceType string, resourceID int64) (domain.Decision, error)
+	CheckPlatformPermission(ctx context.Context, userID int64, requiredPermission string) (domain.Decision, error)
+	GetEffectiveRoles(ctx context.Context, userID int64) ([]domain.EffectiveRole, error)
+	Explain(ctx context.Context, req domain.ExplainRequest) (*domain.Explanation, error)
+}
+
+// RoleService manages roles, permissions and the grants between them
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	GetRole(ctx context.Context, id int64) (*domain.Role, error)
+	CreateRole(ctx context.Context, req domain.RoleRequest) (*domain.Role, error)
+	UpdateRole(ctx context.Context, id int64, req domain.RoleRequest) (*domain.Role, error)
+	DeleteRole(ctx context.Context, id int64) error
+	SetRoleParent(ctx context.Context, roleID int64, parentID *int64) error
+
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	GetPermission(ctx context.Context, id int64) (*domain.Permission, error)
+	CreatePermission(ctx context.Context, req domain.PermissionRequest) (*domain.Permission, error)
+	UpdatePermission(ctx context.Context, id int64, req domain.PermissionRequest) (*domain.Permission, error)
+	DeletePermission(ctx context.Context, id int64) error
+
+	ListRoleGrants(ctx context.Context, roleID int64) ([]domain.RoleGrant, error)
+	GrantPermission(ctx context.Context, roleID int64, req domain.GrantPermissionRequest) (*domain.RoleGrant, error)
+	RevokePermission(ctx context.Context, roleID, permissionID int64) error
+}
+
+// UserService handles user administration
+type UserService interface {
+	SearchUsers(ctx context.Context, search domain.UserSearch) (*domain.UserPage, error)
+	ListRoles(ctx context.Context, userID int64) ([]domain.RoleAssignment, error)
+	AssignRole(ctx context.Context, actorID, userID int64, req domain.AssignRoleRequest) error
+	RevokeRole(ctx context.Context, actorID, userID, roleID int64) error
+	Deactivate(ctx context.Context, actorID, userID int64) error
+	Activate(ctx context.Context, actorID, userID int64) error
+	RevokeSessions(ctx context.Context, actorID, userID int64) error
+	Unlock(ctx context.Context, actorID, userID int64) error
+}
+
+// GroupService manages user groups in the active organization
+type GroupService interface {
+	CreateGroup(ctx context.Context, req domain.CreateGroupRequest) (*domain.Group, error)
+	ListGroups(ctx context.Context) ([]domain.Group, error)
+	AddMember(ctx context.Context, groupID, userID int64) error
+	RemoveMember(ctx context.Context, groupID, userID int64) error
+	AssignRole(ctx context.Context, groupID, roleID int64) error
+	RevokeRole(ctx context.Context, groupID, roleID int64) error
+}
+
+// ServiceAccountService manages machine identities and their client credentials
+type ServiceAccountService interface {
+	Create(ctx context.Context, actorID int64, req domain.CreateServiceAccountRequest) (*domain.ServiceAccountCredentials, error)
+	List(ctx context.Context) ([]domain.ServiceAccount, error)
+	RotateSecret(ctx context.Context, actorID, userID int64) (*domain.ServiceAccountCredentials, error)
+	Delete(ctx context.Context, actorID, userID int64) error
+	IssueToken(ctx context.Context, clientID, clientSecret string) (*domain.TokenResponse, error)
+}
+
+// AccountService handles self-service password changes and resets, and email verification
+type AccountService interface {
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req domain.ResetPasswordRequest) error
+	ChangePassword(ctx context.Context, userID int64, req domain.ChangePasswordRequest) error
+	SendVerificationEmail(ctx context.Context, userID int64) error
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+// MFAService manages TOTP enrollment and recovery codes, and the second step of login
+type MFAService interface {
+	Status(ctx context.Context, userID int64) (*domain.MFAStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, userID int64) (*domain.TOTPSetup, error)
+	ConfirmTOTPEnrollment(ctx context.Context, userID int64, code string) (*domain.RecoveryCodes, error)
+	DisableTOTP(ctx context.Context, userID int64, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) (*domain.RecoveryCodes, error)
+	Enabled(ctx context.Context, userID int64) (bool, error)
+	VerifyCode(ctx context.Context, userID int64, code string) error
+	StartChallenge(ctx context.Context, userID, orgID int64) (string, error)
+	CompleteChallenge(ctx context.Context, token, code string) (*domain.MFAChallenge, error)
+}
+
+// APIKeyService manages personal API keys and authenticates requests made with them
+type APIKeyService interface {
+	Create(ctx context.Context, userID int64, req domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error)
+	List(ctx context.Context, userID int64) ([]domain.APIKey, error)
+	Delete(ctx context.Context, userID, keyID int64) error
+	Authenticate(ctx context.Context, key string) (*domain.APIKey, error)
+}
+
+// OIDCService makes this service an OpenID Connect provider (authorization code flow with PKCE)
+type OIDCService interface {
+	Discovery() domain.OIDCDiscovery
+	CreateClient(ctx context.Context, req domain.CreateOAuthClientRequest) (*domain.CreateOAuthClientResponse, error)
+	ListClients(ctx context.Context) ([]domain.OAuthClient, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	ValidateAuthorizeRequest(ctx context.Context, req domain.AuthorizeRequest) error
+	Authorize(ctx context.Context, req domain.AuthorizeRequest, username, password, otp string) (string, error)
+	Exchange(ctx context.Context, req domain.TokenRequest) (*domain.TokenResponse, error)
+	UserInfo(ctx context.Context, claims *utils.Claims) (*domain.UserInfo, error)
 }
 
 // ProductService handles product-related business logic
 type ProductService interface {
-	CreateProduct(ctx context.Context, req domain.CreateProductRequest, userID int64) (*domain.Product, error)
-	// GetProduct(ctx context.Context, id int64) (*domain.Product, error)
+	CreateProduct(ctx context.Context, req domain.CreateProductRequest, userID, orgID int64) (*domain.Product, error)
+	GetProduct(ctx context.Context, id int64) (*domain.Product, error)
+	ListProducts(ctx context.Context, filter domain.ProductFilter) (*domain.ProductPage, error)
+	UpdateProduct(ctx context.Context, id int64, req domain.UpdateProductRequest) (*domain.Product, error)
+	DeleteProduct(ctx context.Context, id int64) error
 }
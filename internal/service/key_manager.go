@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"rbac/internal/utils"
+	"sync"
+	"time"
+)
+
+// keyReloadMinInterval rate-limits reloads triggered by unknown kids, so
+// garbage tokens cannot turn into a query per request
+const keyReloadMinInterval = 10 * time.Second
+
+// ErrUnknownKey is returned for a kid that no valid signing key has
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeyManager holds the asymmetric keys that sign and verify access tokens.
+// Keys are shared by every instance through the store: the newest key signs,
+// and each key keeps verifying for an overlap window after its successor takes
+// over, so tokens signed just before a rotation stay valid until they expire.
+// Rotation happens on a check interval, so it can be up to one interval late;
+// the overlap must cover the longest token lifetime plus that interval.
+type KeyManager struct {
+	repo      repository.SigningKeyRepository
+	algorithm string
+	rotation  time.Duration // How long a key signs before a new one is generated
+	overlap   time.Duration // How long a key keeps verifying after that
+	check     time.Duration // How often Run reloads keys and rotates when due
+	kek       []byte        // Seals private keys in the store
+
+	// reloadMu lets one unknown kid at a time reload; the others wait for it
+	reloadMu sync.Mutex
+
+	mu       sync.RWMutex
+	keys     []*utils.SigningKey // Newest first
+	byKID    map[string]*utils.SigningKey
+	lastLoad time.Time
+}
+
+// NewKeyManager creates a KeyManager that generates algorithm keys every
+// rotation, checking every check interval. Private keys are stored encrypted
+// with kek (see utils.SealPrivateKey).
+func NewKeyManager(repo repository.SigningKeyRepository, algorithm string, rotation, overlap, check time.Duration, kek []byte) *KeyManager {
+	return &KeyManager{
+		repo:      repo,
+		algorithm: algorithm,
+		rotation:  rotation,
+		overlap:   overlap,
+		check:     check,
+		kek:       kek,
+		byKID:     make(map[string]*utils.SigningKey),
+	}
+}
+
+// Algorithm returns the algorithm new keys are generated with
+func (m *KeyManager) Algorithm() string {
+	return m.algorithm
+}
+
+// SigningKey returns the key new tokens are signed with
+func (m *KeyManager) SigningKey() (*utils.SigningKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.keys) == 0 {
+		return nil, errors.New("no signing key loaded")
+	}
+	return m.keys[0], nil
+}
+
+// Lookup returns the key for kid. A kid this instance has not seen yet may
+// belong to a key another instance just generated, so it triggers a reload.
+// Lookup satisfies utils.KeyLookup.
+func (m *KeyManager) Lookup(kid string) (*utils.SigningKey, error) {
+	if key := m.find(kid); key != nil {
+		return key, nil
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	// The reload this call waited for may have brought the key in
+	if key := m.find(kid); key != nil {
+		return key, nil
+	}
+
+	m.mu.RLock()
+	recent := time.Since(m.lastLoad) < keyReloadMinInterval
+	m.mu.RUnlock()
+	if !recent {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.Load(ctx); err != nil {
+			return nil, err
+		}
+		if key := m.find(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func (m *KeyManager) find(kid string) *utils.SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key := m.byKID[kid]
+	if key == nil || time.Now().After(key.ExpiresAt) {
+		return nil
+	}
+	return key
+}
+
+// JWKS returns the public halves of every key that still verifies tokens
+func (m *KeyManager) JWKS() utils.JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := utils.JWKS{Keys: []utils.JWK{}}
+	now := time.Now()
+	for _, key := range m.keys {
+		if now.Before(key.ExpiresAt) {
+			set.Keys = append(set.Keys, key.PublicJWK())
+		}
+	}
+	return set
+}
+
+// Load replaces the in-memory keys with the valid keys in the store
+func (m *KeyManager) Load(ctx context.Context) error {
+	records, err := m.repo.ListValid(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*utils.SigningKey, 0, len(records))
+	byKID := make(map[string]*utils.SigningKey, len(records))
+	for _, r := range records {
+		pemData, err := utils.OpenPrivateKey(r.SealedPrivateKey, m.kek, r.KID)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", r.KID, err)
+		}
+		private, err := utils.ParsePrivateKey(pemData)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", r.KID, err)
+		}
+		key := &utils.SigningKey{
+			KID:       r.KID,
+			Algorithm: r.Algorithm,
+			Private:   private,
+			CreatedAt: r.CreatedAt,
+			ExpiresAt: r.ExpiresAt,
+		}
+		keys = append(keys, key)
+		byKID[key.KID] = key
+	}
+
+	m.mu.Lock()
+	m.keys, m.byKID, m.lastLoad = keys, byKID, time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// Rotate generates a new signing key if there is none, the newest is older
+// than the rotation period, or the configured algorithm changed
+func (m *KeyManager) Rotate(ctx context.Context) error {
+	m.mu.RLock()
+	due := len(m.keys) == 0 ||
+		time.Since(m.keys[0].CreatedAt) >= m.rotation ||
+		m.keys[0].Algorithm != m.algorithm
+	m.mu.RUnlock()
+	if !due {
+		return nil
+	}
+
+	key, err := utils.GenerateSigningKey(m.algorithm)
+	if err != nil {
+		return err
+	}
+	pemData, err := utils.MarshalPrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	sealed, err := utils.SealPrivateKey(pemData, m.kek, key.KID)
+	if err != nil {
+		return err
+	}
+	// Truncated to the second, as the store keeps it, so every instance agrees
+	now := time.Now().Truncate(time.Second)
+	record := &domain.SigningKeyRecord{
+		KID:              key.KID,
+		Algorithm:        key.Algorithm,
+		SealedPrivateKey: sealed,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(m.rotation + m.overlap),
+	}
+	if err := m.repo.Create(ctx, record); err != nil {
+		return err
+	}
+	log.Printf("Generated %s signing key %s", key.Algorithm, key.KID)
+
+	// Reload rather than prepend, in case another instance rotated at the same time
+	return m.Load(ctx)
+}
+
+// Run reloads, rotates and purges expired keys until ctx is cancelled
+func (m *KeyManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.check)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.Load(ctx); err != nil {
+			log.Printf("Failed to load signing keys: %v", err)
+			continue
+		}
+		if err := m.Rotate(ctx); err != nil {
+			log.Printf("Signing key rotation failed: %v", err)
+		}
+		if _, err := m.repo.DeleteExpired(ctx); err != nil {
+			log.Printf("Failed to purge expired signing keys: %v", err)
+		}
+	}
+}
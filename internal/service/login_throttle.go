@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"strconv"
+	"time"
+)
+
+// LoginThrottledError is returned when a login is refused without checking the
+// password, because the account or client IP failed too often recently
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // Locked out, rather than waiting out the backoff
+}
+
+func (e *LoginThrottledError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if e.Locked {
+		return fmt.Sprintf("too many failed login attempts; locked for %s", wait)
+	}
+	return fmt.Sprintf("too many failed login attempts; try again in %s", wait)
+}
+
+// LoginThrottlePolicy configures a LoginThrottle. A threshold of 0 disables
+// lockout for that scope.
+type LoginThrottlePolicy struct {
+	// FreeAttempts is how many failures are tolerated before backoff starts
+	FreeAttempts int
+	// BackoffBase is the wait after the first failure past FreeAttempts; it
+	// doubles with each further failure up to BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Failures within FailureWindow of each other count towards a lockout
+	FailureWindow           time.Duration
+	AccountLockoutThreshold int
+	IPLockoutThreshold      int
+	LockoutDuration         time.Duration
+	// MFALockoutThreshold is how many attempts at a second factor in a row may
+	// fail before the user is locked out of every path that checks one
+	MFALockoutThreshold int
+}
+
+// LoginThrottle slows down and then locks out password guessing. Failures are
+// counted per account and per client IP in the store, so every instance
+// enforces the same limits, and each refused attempt skips the password hash
+// comparison altogether.
+//
+// An attempt is counted as a failure when it is reserved, before the password
+// is compared, and given back if the password turns out right. Reserving checks
+// and counts under a row lock, so concurrent attempts are decided one at a time
+// and cannot all pass a check before any of them is counted.
+type LoginThrottle struct {
+	repo      repository.LoginFailureRepository
+	tx        repository.Transactor
+	auditRepo repository.AuditRepository
+	policy    LoginThrottlePolicy
+}
+
+// NewLoginThrottle creates a LoginThrottle enforcing policy
+func NewLoginThrottle(repo repository.LoginFailureRepository, tx repository.Transactor, auditRepo repository.AuditRepository, policy LoginThrottlePolicy) *LoginThrottle {
+	return &LoginThrottle{repo: repo, tx: tx, auditRepo: auditRepo, policy: policy}
+}
+
+// ReserveIP counts an attempt from the client IP, or refuses it if the IP is
+// backing off or locked out. An unknown IP (empty) is not tracked.
+func (t *LoginThrottle) ReserveIP(ctx context.Context, ip string) error {
+	if ip == "" {
+		return nil
+	}
+	return t.reserve(ctx, domain.ThrottleScopeIP, ip)
+}
+
+// ReserveAccount counts an attempt against the account, or refuses it if the
+// account is backing off or locked out
+func (t *LoginThrottle) ReserveAccount(ctx context.Context, userID int64) error {
+	return t.reserve(ctx, domain.ThrottleScopeAccount, accountSubject(userID))
+}
+
+// Failed reports that the attempt reserved from ip, and against user when the
+// username exists, had the wrong password. Whichever reached its threshold is
+// locked out.
+func (t *LoginThrottle) Failed(ctx context.Context, ip string, user *domain.User) error {
+	if ip != "" {
+		if err := t.lockIfOver(ctx, domain.ThrottleScopeIP, ip, t.policy.IPLockoutThreshold, nil, ip); err != nil {
+			return err
+		}
+	}
+	if user != nil {
+		return t.lockIfOver(ctx, domain.ThrottleScopeAccount, accountSubject(user.ID), t.policy.AccountLockoutThreshold, &user.ID, ip)
+	}
+	return nil
+}
+
+// Succeeded reports that the attempt reserved from ip against userID had the
+// right password: the account's failures are cleared, and the attempt counted
+// against the IP is given back
+func (t *LoginThrottle) Succeeded(ctx context.Context, ip string, userID int64) error {
+	if ip != "" {
+		if err := t.repo.Release(ctx, domain.ThrottleScopeIP, ip); err != nil {
+			return err
+		}
+	}
+	return t.ResetAccount(ctx, userID)
+}
+
+// ResetAccount clears the account's failures and lifts any lockout, after a
+// successful login or by an admin. Failures of IPs are kept: one valid account
+// must not let a client keep guessing the passwords of others.
+func (t *LoginThrottle) ResetAccount(ctx context.Context, userID int64) error {
+	return t.repo.Delete(ctx, domain.ThrottleScopeAccount, accountSubject(userID))
+}
+
+// ReserveMFA counts an attempt at a second factor for userID before the code
+// is checked. Like the password reservations it decides under a lock on the
+// user's row, so parallel guesses cannot all get in under the limit. The
+// attempt past MFALockoutThreshold locks the user out; ResetMFA after a
+// correct code starts the count over.
+func (t *LoginThrottle) ReserveMFA(ctx context.Context, userID int64) error {
+	threshold := t.policy.MFALockoutThreshold
+	if threshold <= 0 {
+		return nil
+	}
+	subject := accountSubject(userID)
+	if err := t.repo.Init(ctx, domain.ThrottleScopeMFA, subject, time.Now()); err != nil {
+		return err
+	}
+
+	var failures int
+	var lockedUntil *time.Time
+	err := t.tx.WithinTx(ctx, func(tx repository.TxRepositories) error {
+		now := time.Now()
+		record, err := tx.LoginFailures.FindForUpdate(ctx, domain.ThrottleScopeMFA, subject)
+		if err != nil && err != repository.ErrNotFound { // Not found: swept since Init
+			return err
+		}
+		// Attempts during a lockout are not counted, so the count starts over after it
+		if record != nil && record.LockedUntil != nil && now.Before(*record.LockedUntil) {
+			return &LoginThrottledError{RetryAfter: record.LockedUntil.Sub(now), Locked: true}
+		}
+
+		record, err = tx.LoginFailures.RecordFailure(ctx, domain.ThrottleScopeMFA, subject, now, now.Add(-t.policy.FailureWindow))
+		if err != nil {
+			return err
+		}
+		if record.Failures <= threshold {
+			return nil
+		}
+		// Locked in the same transaction; the attempt is refused after it commits
+		until := now.Add(t.policy.LockoutDuration)
+		failures, lockedUntil = record.Failures-1, &until
+		return tx.LoginFailures.Lock(ctx, domain.ThrottleScopeMFA, subject, until)
+	})
+	if err != nil || lockedUntil == nil {
+		return err
+	}
+
+	err = t.auditRepo.Record(ctx, &domain.AuditEvent{
+		Type:   domain.AuditMFALocked,
+		UserID: &userID,
+		Details: map[string]interface{}{
+			"failures":     failures,
+			"locked_until": *lockedUntil,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return &LoginThrottledError{RetryAfter: t.policy.LockoutDuration, Locked: true}
+}
+
+// ResetMFA clears the user's attempts at a second factor and lifts any
+// lockout, after a correct code or by an admin
+func (t *LoginThrottle) ResetMFA(ctx context.Context, userID int64) error {
+	return t.repo.Delete(ctx, domain.ThrottleScopeMFA, accountSubject(userID))
+}
+
+// reserve counts an attempt against subject unless it is locked out or backing
+// off, deciding under a lock on the subject's row
+func (t *LoginThrottle) reserve(ctx context.Context, scope, subject string) error {
+	// Created beforehand: locking a row that does not exist yet would let two
+	// attempts deadlock on inserting it
+	if err := t.repo.Init(ctx, scope, subject, time.Now()); err != nil {
+		return err
+	}
+	return t.tx.WithinTx(ctx, func(tx repository.TxRepositories) error {
+		now := time.Now()
+		record, err := tx.LoginFailures.FindForUpdate(ctx, scope, subject)
+		if err != nil && err != repository.ErrNotFound { // Not found: swept since Init
+			return err
+		}
+		if record != nil {
+			if err := t.refusal(record, now); err != nil {
+				return err
+			}
+		}
+		_, err = tx.LoginFailures.RecordFailure(ctx, scope, subject, now, now.Add(-t.policy.FailureWindow))
+		return err
+	})
+}
+
+// refusal returns a *LoginThrottledError if record is locked out or backing off at now
+func (t *LoginThrottle) refusal(record *domain.LoginFailures, now time.Time) error {
+	if record.LockedUntil != nil && now.Before(*record.LockedUntil) {
+		return &LoginThrottledError{RetryAfter: record.LockedUntil.Sub(now), Locked: true}
+	}
+	if next := record.LastFailureAt.Add(t.backoff(record.Failures)); now.Before(next) {
+		return &LoginThrottledError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+// backoff is the wait imposed after the given number of consecutive failures
+func (t *LoginThrottle) backoff(failures int) time.Duration {
+	excess := failures - t.policy.FreeAttempts
+	if excess <= 0 || t.policy.BackoffBase <= 0 {
+		return 0
+	}
+	wait := t.policy.BackoffBase
+	for i := 1; i < excess && wait < t.policy.BackoffMax; i++ {
+		wait *= 2
+	}
+	if wait > t.policy.BackoffMax {
+		wait = t.policy.BackoffMax
+	}
+	return wait
+}
+
+// lockIfOver locks the subject out once its failures reach threshold
+func (t *LoginThrottle) lockIfOver(ctx context.Context, scope, subject string, threshold int, userID *int64, ip string) error {
+	if threshold <= 0 {
+		return nil
+	}
+	record, err := t.repo.Find(ctx, scope, subject)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if record.Failures < threshold {
+		return nil
+	}
+
+	now := time.Now()
+
+	until := now.Add(t.policy.LockoutDuration)
+	if err := t.repo.Lock(ctx, scope, subject, until); err != nil {
+		return err
+	}
+
+	eventType := domain.AuditAccountLocked
+	if scope == domain.ThrottleScopeIP {
+		eventType = domain.AuditIPLocked
+	}
+	return t.auditRepo.Record(ctx, &domain.AuditEvent{
+		Type:   eventType,
+		UserID: userID,
+		Details: map[string]interface{}{
+			"ip":           ip,
+			"failures":     record.Failures,
+			"locked_until": until,
+		},
+	})
+}
+
+func accountSubject(userID int64) string {
+	return strconv.FormatInt(userID, 10)
+}
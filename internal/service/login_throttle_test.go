@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"rbac/internal/domain"
+	"testing"
+	"time"
+)
+
+var testThrottlePolicy = LoginThrottlePolicy{
+	FreeAttempts:    3,
+	BackoffBase:     time.Second,
+	BackoffMax:      10 * time.Second,
+	FailureWindow:   time.Hour,
+	LockoutDuration: 15 * time.Minute,
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   LoginThrottlePolicy
+		failures int
+		want     time.Duration
+	}{
+		{"no failures", testThrottlePolicy, 0, 0},
+		{"within free attempts", testThrottlePolicy, 3, 0},
+		{"first past free attempts", testThrottlePolicy, 4, time.Second},
+		{"second past free attempts", testThrottlePolicy, 5, 2 * time.Second},
+		{"third past free attempts", testThrottlePolicy, 6, 4 * time.Second},
+		{"fourth past free attempts", testThrottlePolicy, 7, 8 * time.Second},
+		{"capped at max", testThrottlePolicy, 8, 10 * time.Second},
+		{"far past max", testThrottlePolicy, 1000, 10 * time.Second},
+		{"backoff disabled", LoginThrottlePolicy{FreeAttempts: 3, BackoffMax: time.Minute}, 10, 0},
+		{"base above max", LoginThrottlePolicy{BackoffBase: time.Minute, BackoffMax: time.Second}, 1, time.Second},
+	}
+	for _, tt := range tests {
+		throttle := &LoginThrottle{policy: tt.policy}
+		if got := throttle.backoff(tt.failures); got != tt.want {
+			t.Errorf("%s: backoff(%d) = %v, want %v", tt.name, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottleRefusal(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		until := now.Add(d)
+		return &until
+	}
+	tests := []struct {
+		name       string
+		record     domain.LoginFailures
+		wantRefuse bool
+		wantLocked bool
+		wantRetry  time.Duration
+	}{
+		{"within free attempts", domain.LoginFailures{Failures: 3, LastFailureAt: now}, false, false, 0},
+		{"backing off", domain.LoginFailures{Failures: 5, LastFailureAt: now.Add(-time.Second)}, true, false, time.Second},
+		{"backoff waited out", domain.LoginFailures{Failures: 5, LastFailureAt: now.Add(-2 * time.Second)}, false, false, 0},
+		{"locked out", domain.LoginFailures{Failures: 0, LastFailureAt: now, LockedUntil: at(time.Minute)}, true, true, time.Minute},
+		{"lockout over", domain.LoginFailures{Failures: 0, LastFailureAt: now.Add(-time.Hour), LockedUntil: at(-time.Second)}, false, false, 0},
+		// A lockout wins over the backoff, which may well be shorter
+		{"locked out and backing off", domain.LoginFailures{Failures: 5, LastFailureAt: now, LockedUntil: at(time.Minute)}, true, true, time.Minute},
+	}
+	throttle := &LoginThrottle{policy: testThrottlePolicy}
+	for _, tt := range tests {
+		err := throttle.refusal(&tt.record, now)
+		if !tt.wantRefuse {
+			if err != nil {
+				t.Errorf("%s: refusal = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var throttled *LoginThrottledError
+		if !errors.As(err, &throttled) {
+			t.Errorf("%s: refusal = %v, want a *LoginThrottledError", tt.name, err)
+			continue
+		}
+		if throttled.Locked != tt.wantLocked || throttled.RetryAfter != tt.wantRetry {
+			t.Errorf("%s: refusal = {RetryAfter: %v, Locked: %v}, want {RetryAfter: %v, Locked: %v}",
+				tt.name, throttled.RetryAfter, throttled.Locked, tt.wantRetry, tt.wantLocked)
+		}
+	}
+}
+
+func TestLoginThrottledErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  LoginThrottledError
+		want string
+	}{
+		{LoginThrottledError{RetryAfter: 4 * time.Second}, "too many failed login attempts; try again in 4s"},
+		{LoginThrottledError{RetryAfter: 1600 * time.Millisecond}, "too many failed login attempts; try again in 2s"},
+		// Never tells the client to retry in 0s
+		{LoginThrottledError{RetryAfter: 100 * time.Millisecond}, "too many failed login attempts; try again in 1s"},
+		{LoginThrottledError{RetryAfter: 15 * time.Minute, Locked: true}, "too many failed login attempts; locked for 15m0s"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"rbac/internal/utils"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidMFACode is returned for a wrong, reused or malformed TOTP or recovery code
+	ErrInvalidMFACode = errors.New("invalid verification code")
+	// ErrInvalidMFAChallenge is returned for an unknown, expired, used or exhausted MFA challenge
+	ErrInvalidMFAChallenge = errors.New("MFA challenge is invalid or expired")
+	// ErrMFANotEnrolled is returned when a code is checked for a user without MFA
+	ErrMFANotEnrolled = errors.New("MFA is not enabled")
+	// ErrMFAAlreadyEnabled is returned when enrolling while MFA is already on
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled; disable it first")
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxMFAAttempts is how many codes one challenge accepts
+	maxMFAAttempts = 5
+)
+
+type mfaVerifiedKey struct{}
+
+// WithMFAVerified records that userID completed multi-factor authentication
+// in the session behind the request
+func WithMFAVerified(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, mfaVerifiedKey{}, userID)
+}
+
+// mfaVerified reports whether the request's session completed MFA as userID
+func mfaVerified(ctx context.Context, userID int64) bool {
+	verified, ok := ctx.Value(mfaVerifiedKey{}).(int64)
+	return ok && verified == userID
+}
+
+type mfaService struct {
+	mfaRepo       repository.MFARepository
+	challengeRepo repository.MFAChallengeRepository
+	userRepo      repository.UserRepository
+	auditRepo     repository.AuditRepository
+	throttle      *LoginThrottle
+	issuer        string
+	challengeTTL  time.Duration
+}
+
+// NewMFAService creates a new MFAService. issuer labels the account in
+// authenticator apps; challengeTTL bounds the time between the two login steps.
+func NewMFAService(mfaRepo repository.MFARepository, challengeRepo repository.MFAChallengeRepository, userRepo repository.UserRepository, auditRepo repository.AuditRepository, throttle *LoginThrottle, issuer string, challengeTTL time.Duration) MFAService {
+	return &mfaService{
+		mfaRepo:       mfaRepo,
+		challengeRepo: challengeRepo,
+		userRepo:      userRepo,
+		auditRepo:     auditRepo,
+		throttle:      throttle,
+		issuer:        issuer,
+		challengeTTL:  challengeTTL,
+	}
+}
+
+// Status reports whether the user has MFA enabled and how many recovery codes are left
+func (s *mfaService) Status(ctx context.Context, userID int64) (*domain.MFAStatus, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &domain.MFAStatus{Enabled: enabled}
+	if enabled {
+		if status.RecoveryCodesRemaining, err = s.mfaRepo.CountUnusedRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new secret. MFA is not enforced until the
+// user proves their authenticator works with ConfirmTOTPEnrollment.
+func (s *mfaService) BeginTOTPEnrollment(ctx context.Context, userID int64) (*domain.TOTPSetup, error) {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return nil, ErrAPIKeyNotAllowed
+	}
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SaveTOTP(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &domain.TOTPSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables MFA once code matches the pending secret, and
+// returns the first set of recovery codes
+func (s *mfaService) ConfirmTOTPEnrollment(ctx context.Context, userID int64, code string) (*domain.RecoveryCodes, error) {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return nil, ErrAPIKeyNotAllowed
+	}
+	enrollment, err := s.mfaRepo.FindTOTP(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, newValidationError("no TOTP enrollment in progress")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err := s.checkTOTP(ctx, enrollment, strings.TrimSpace(code)); err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.EnableTOTP(ctx, userID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, domain.AuditMFAEnabled, userID, nil); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns MFA off after checking a current code, so a stolen
+// session alone cannot remove the second factor
+func (s *mfaService) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return ErrAPIKeyNotAllowed
+	}
+	if err := s.VerifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+	return s.record(ctx, domain.AuditMFADisabled, userID, nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) (*domain.RecoveryCodes, error) {
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return nil, ErrAPIKeyNotAllowed
+	}
+	if err := s.VerifyCode(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, err := s.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, domain.AuditRecoveryCodesIssued, userID, nil); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Enabled reports whether the user has a confirmed TOTP enrollment
+func (s *mfaService) Enabled(ctx context.Context, userID int64) (bool, error) {
+	enrollment, err := s.mfaRepo.FindTOTP(ctx, userID)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrollment.EnabledAt != nil, nil
+}
+
+// VerifyCode checks a TOTP code, or consumes a recovery code, for a user with
+// MFA enabled. Every caller goes through here, so wrong codes count towards one
+// lockout per user, refused with a *LoginThrottledError.
+func (s *mfaService) VerifyCode(ctx context.Context, userID int64, code string) error {
+	enrollment, err := s.mfaRepo.FindTOTP(ctx, userID)
+	if err == repository.ErrNotFound || (err == nil && enrollment.EnabledAt == nil) {
+		return ErrMFANotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.throttle.ReserveMFA(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.checkCode(ctx, enrollment, strings.TrimSpace(code)); err != nil {
+		return err
+	}
+	return s.throttle.ResetMFA(ctx, userID)
+}
+
+// checkCode checks a TOTP code or consumes a recovery code
+func (s *mfaService) checkCode(ctx context.Context, enrollment *domain.TOTPEnrollment, code string) error {
+	userID := enrollment.UserID
+	if isTOTPCode(code) {
+		return s.checkTOTP(ctx, enrollment, code)
+	}
+
+	err := s.mfaRepo.UseRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if err == repository.ErrNotFound {
+		return ErrInvalidMFACode
+	}
+	if err != nil {
+		return err
+	}
+	remaining, err := s.mfaRepo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.record(ctx, domain.AuditRecoveryCodeUsed, userID, map[string]interface{}{"remaining": remaining})
+}
+
+// StartChallenge begins the second step of a login into orgID and returns
+// the challenge token
+func (s *mfaService) StartChallenge(ctx context.Context, userID, orgID int64) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	challenge := &domain.MFAChallenge{
+		TokenHash: utils.HashToken(token),
+		UserID:    userID,
+		OrgID:     orgID,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	}
+	if err := s.challengeRepo.Create(ctx, challenge); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteChallenge checks code against the challenge's user and consumes the
+// challenge. Each challenge accepts a few codes before it is spent.
+func (s *mfaService) CompleteChallenge(ctx context.Context, token, code string) (*domain.MFAChallenge, error) {
+	challenge, err := s.challengeRepo.FindByHash(ctx, utils.HashToken(token))
+	if err == repository.ErrNotFound {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	// Counted before the code is checked, so parallel guesses cannot exceed the limit
+	if err := s.challengeRepo.RecordAttempt(ctx, challenge.ID, maxMFAAttempts); err == repository.ErrNotFound {
+		return nil, ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch err := s.VerifyCode(ctx, challenge.UserID, code); err {
+	case nil:
+	case ErrMFANotEnrolled:
+		// MFA was turned off since the password step; start over
+		return nil, ErrInvalidMFAChallenge
+	default:
+		return nil, err
+	}
+
+	if err := s.challengeRepo.MarkUsed(ctx, challenge.ID); err == repository.ErrNotFound {
+		return nil, ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// checkTOTP accepts a code for the enrollment's secret, at most once per time step
+func (s *mfaService) checkTOTP(ctx context.Context, enrollment *domain.TOTPEnrollment, code string) error {
+	step, ok := utils.ValidateTOTP(enrollment.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	if err := s.mfaRepo.UseTOTPStep(ctx, enrollment.UserID, step); err == repository.ErrNotFound {
+		return ErrInvalidMFACode // Replayed
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// issueRecoveryCodes replaces the user's recovery codes with a new set
+func (s *mfaService) issueRecoveryCodes(ctx context.Context, userID int64) (*domain.RecoveryCodes, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &domain.RecoveryCodes{Codes: codes}, nil
+}
+
+func (s *mfaService) record(ctx context.Context, eventType string, userID int64, details map[string]interface{}) error {
+	return s.auditRepo.Record(ctx, &domain.AuditEvent{
+		Type:        eventType,
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Details:     details,
+	})
+}
+
+// isTOTPCode reports whether code has the shape of a TOTP code rather than a recovery code
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/url"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"rbac/internal/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// authorizationCodeTTL is how long a client has to exchange a code
+const authorizationCodeTTL = time.Minute
+
+// Scopes this provider understands; "openid" is required
+var supportedScopes = []string{"openid", "profile", "email"}
+
+type oidcService struct {
+	clientRepo  repository.OAuthClientRepository
+	codeRepo    repository.AuthorizationCodeRepository
+	userRepo    repository.UserRepository
+	orgRepo     repository.OrganizationRepository
+	authSvc     AuthService
+	mfaSvc      MFAService
+	keys        *KeyManager
+	revocations *TokenRevocationList
+	issuer      string
+	accessTTL   time.Duration
+}
+
+// NewOIDCService creates a new OIDCService that issues tokens as issuer,
+// the provider's external base URL
+func NewOIDCService(clientRepo repository.OAuthClientRepository, codeRepo repository.AuthorizationCodeRepository, userRepo repository.UserRepository, orgRepo repository.OrganizationRepository, authSvc AuthService, mfaSvc MFAService, keys *KeyManager, revocations *TokenRevocationList, issuer string, accessTTL time.Duration) OIDCService {
+	return &oidcService{
+		clientRepo:  clientRepo,
+		codeRepo:    codeRepo,
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		authSvc:     authSvc,
+		mfaSvc:      mfaSvc,
+		keys:        keys,
+		revocations: revocations,
+		issuer:      strings.TrimSuffix(issuer, "/"),
+		accessTTL:   accessTTL,
+	}
+}
+
+// Discovery returns the provider metadata
+func (s *oidcService) Discovery() domain.OIDCDiscovery {
+	return domain.OIDCDiscovery{
+		Issuer:                            s.issuer,
+		AuthorizationEndpoint:             s.issuer + "/authorize",
+		TokenEndpoint:                     s.issuer + "/token",
+		UserInfoEndpoint:                  s.issuer + "/userinfo",
+		JWKSURI:                           s.issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.keys.Algorithm()},
+		ScopesSupported:                   supportedScopes,
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "email", "org_id", "roles"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+	}
+}
+
+// --- Clients ---
+
+// CreateClient registers a client. Confidential clients get a secret, returned only here.
+func (s *oidcService) CreateClient(ctx context.Context, req domain.CreateOAuthClientRequest) (*domain.CreateOAuthClientResponse, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, newValidationError("client name is required")
+	}
+	if len(req.RedirectURIs) == 0 {
+		return nil, newValidationError("at least one redirect URI is required")
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return nil, err
+		}
+	}
+
+	clientID, err := utils.RandomID()
+	if err != nil {
+		return nil, err
+	}
+	client := &domain.OAuthClient{ClientID: clientID, Name: req.Name, RedirectURIs: req.RedirectURIs}
+	resp := &domain.CreateOAuthClientResponse{}
+	if !req.Public {
+		if resp.ClientSecret, err = utils.GenerateOpaqueToken(); err != nil {
+			return nil, err
+		}
+		client.SecretHash = utils.HashToken(resp.ClientSecret)
+	}
+
+	if err := s.clientRepo.Create(ctx, client); err != nil {
+		return nil, err
+	}
+	resp.Client = *client
+	return resp, nil
+}
+
+func (s *oidcService) ListClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	return s.clientRepo.List(ctx)
+}
+
+func (s *oidcService) DeleteClient(ctx context.Context, clientID string) error {
+	return s.clientRepo.Delete(ctx, clientID)
+}
+
+// validateRedirectURI requires an absolute URL without a fragment, over HTTPS
+// unless it points at the local machine
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return newValidationError("redirect URI " + strconv.Quote(raw) + " must be an absolute URL")
+	}
+	if u.Fragment != "" {
+		return newValidationError("redirect URI " + strconv.Quote(raw) + " must not have a fragment")
+	}
+	host := u.Hostname()
+	local := host == "localhost" || host == "127.0.0.1" || host == "::1"
+	if u.Scheme != "https" && !(u.Scheme == "http" && local) {
+		return newValidationError("redirect URI " + strconv.Quote(raw) + " must use https")
+	}
+	return nil
+}
+
+// --- Authorization code flow ---
+
+// ValidateAuthorizeRequest checks an authorization request. A ValidationError
+// means the client or redirect URI cannot be trusted, so the error must be shown
+// to the user; an *OAuthError can be returned to the client's redirect URI.
+func (s *oidcService) ValidateAuthorizeRequest(ctx context.Context, req domain.AuthorizeRequest) error {
+	client, err := s.clientRepo.FindByClientID(ctx, req.ClientID)
+	if err == repository.ErrNotFound {
+		return newValidationError("unknown client")
+	}
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return newValidationError("redirect_uri is not registered for this client")
+	}
+
+	if req.ResponseType != "code" {
+		return newOAuthError("unsupported_response_type", "only response_type=code is supported")
+	}
+	if !slices.Contains(strings.Fields(req.Scope), "openid") {
+		return newOAuthError("invalid_scope", "scope must include openid")
+	}
+	// PKCE is mandatory for every client, and plain challenges are refused
+	if req.CodeChallenge == "" {
+		return newOAuthError("invalid_request", "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return newOAuthError("invalid_request", "code_challenge_method must be S256")
+	}
+	return nil
+}
+
+// Authorize signs the user in and returns an authorization code for the client.
+// The code is for the organization in req.OrgID, or the user's oldest
+// membership if the request names none, as in Login.
+// Wrong credentials return ErrInvalidCredentials, and a wrong or missing code for a
+// user enrolled in MFA ErrInvalidMFACode, so the login form can be shown again.
+func (s *oidcService) Authorize(ctx context.Context, req domain.AuthorizeRequest, username, password, otp string) (string, error) {
+	if err := s.ValidateAuthorizeRequest(ctx, req); err != nil {
+		return "", err
+	}
+	user, err := s.authSvc.VerifyCredentials(ctx, username, password)
+	if err != nil {
+		return "", err
+	}
+	// Users enrolled in MFA enter their code on the same form
+	mfa, err := s.mfaSvc.Enabled(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if mfa {
+		if err := s.mfaSvc.VerifyCode(ctx, user.ID, otp); err != nil {
+			return "", err
+		}
+	}
+	orgID, err := resolveLoginOrg(ctx, s.orgRepo, user.ID, req.OrgID)
+	if err == ErrNotOrgMember || err == ErrNoOrganization {
+		return "", newOAuthError("access_denied", err.Error())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	stored := &domain.AuthorizationCode{
+		CodeHash:      utils.HashToken(code),
+		ClientID:      req.ClientID,
+		UserID:        user.ID,
+		OrgID:         orgID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         filterScopes(req.Scope),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      now,
+		MFA:           mfa,
+		ExpiresAt:     now.Add(authorizationCodeTTL),
+	}
+	if err := s.codeRepo.Create(ctx, stored); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Exchange redeems an authorization code for an access token and an ID token.
+// Protocol failures are returned as *OAuthError.
+func (s *oidcService) Exchange(ctx context.Context, req domain.TokenRequest) (*domain.TokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, newOAuthError("unsupported_grant_type", "only authorization_code is supported")
+	}
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.codeRepo.FindByHash(ctx, utils.HashToken(req.Code))
+	if err == repository.ErrNotFound {
+		return nil, newOAuthError("invalid_grant", "unknown authorization code")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code.UsedAt != nil {
+		if err := s.revokeCodeToken(ctx, code); err != nil {
+			return nil, err
+		}
+		return nil, newOAuthError("invalid_grant", "authorization code expired or already used")
+	}
+	if time.Now().After(code.ExpiresAt) {
+		return nil, newOAuthError("invalid_grant", "authorization code expired or already used")
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, newOAuthError("invalid_grant", "authorization code was issued to another client or redirect_uri")
+	}
+	if !utils.VerifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		return nil, newOAuthError("invalid_grant", "code_verifier does not match code_challenge")
+	}
+	jti, err := utils.RandomID()
+	if err != nil {
+		return nil, err
+	}
+	// The token's issue time is recorded with the code, from this clock, so a
+	// revocation on reuse lasts exactly as long as the token
+	issuedAt := time.Now()
+	if err := s.codeRepo.MarkUsed(ctx, code.CodeHash, jti, issuedAt); err == repository.ErrNotFound {
+		// Another exchange of the same code won the race
+		if used, err := s.codeRepo.FindByHash(ctx, code.CodeHash); err == nil {
+			if err := s.revokeCodeToken(ctx, used); err != nil {
+				return nil, err
+			}
+		}
+		return nil, newOAuthError("invalid_grant", "authorization code expired or already used")
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, code.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, newOAuthError("invalid_grant", ErrAccountDeactivated.Error())
+	}
+	roles, err := s.roleNames(ctx, user.ID, code.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.keys.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+	amr := utils.SessionAMR(code.MFA)
+	accessToken, err := utils.GenerateClientToken(user.ID, code.OrgID, amr, client.ClientID, code.Scope, jti, key, issuedAt, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scopes := strings.Fields(code.Scope)
+	claims := &utils.IDTokenClaims{
+		Nonce:    code.Nonce,
+		AuthTime: jwt.NewNumericDate(code.AuthTime),
+		AMR:      amr,
+		OrgID:    code.OrgID,
+		Roles:    roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		},
+	}
+	if slices.Contains(scopes, "profile") {
+		claims.PreferredUsername = user.Username
+	}
+	if slices.Contains(scopes, "email") {
+		claims.Email = user.Email
+	}
+	idToken, err := utils.GenerateIDToken(claims, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.accessTTL / time.Second),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo returns the claims about the holder of a client access token,
+// limited to the scopes the client was granted
+func (s *oidcService) UserInfo(ctx context.Context, claims *utils.Claims) (*domain.UserInfo, error) {
+	user, err := s.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roleNames(ctx, claims.UserID, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	info := &domain.UserInfo{Subject: strconv.FormatInt(user.ID, 10), Roles: roles}
+	if claims.HasScope("profile") {
+		info.PreferredUsername = user.Username
+	}
+	if claims.HasScope("email") {
+		info.Email = user.Email
+	}
+	return info, nil
+}
+
+// revokeCodeToken revokes the access token issued for a code that is presented
+// again: the code has leaked, so that token may be in the wrong hands
+// (RFC 6749 section 4.1.2)
+func (s *oidcService) revokeCodeToken(ctx context.Context, code *domain.AuthorizationCode) error {
+	if code.UsedAt == nil || code.AccessTokenJTI == "" {
+		return nil
+	}
+	return s.revocations.Revoke(ctx, domain.RevokedToken{
+		JTI:       code.AccessTokenJTI,
+		UserID:    code.UserID,
+		ExpiresAt: code.UsedAt.Add(s.accessTTL),
+	})
+}
+
+// authenticateClient checks the client's secret, or for public clients that none was sent
+func (s *oidcService) authenticateClient(ctx context.Context, clientID, secret string) (*domain.OAuthClient, error) {
+	client, err := s.clientRepo.FindByClientID(ctx, clientID)
+	if err == repository.ErrNotFound {
+		return nil, newOAuthError("invalid_client", "unknown client")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic() {
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, newOAuthError("invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+// roleNames lists the names of the user's effective roles, without duplicates
+func (s *oidcService) roleNames(ctx context.Context, userID, orgID int64) ([]string, error) {
+	effective, err := s.userRepo.GetEffectiveRoles(ctx, userID, orgID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	seen := map[string]bool{}
+	for _, r := range effective {
+		if !seen[r.Role.Name] {
+			seen[r.Role.Name] = true
+			names = append(names, r.Role.Name)
+		}
+	}
+	return names, nil
+}
+
+// filterScopes keeps the supported scopes of a space-separated scope string
+func filterScopes(scope string) string {
+	var kept []string
+	for _, s := range strings.Fields(scope) {
+		if slices.Contains(supportedScopes, s) && !slices.Contains(kept, s) {
+			kept = append(kept, s)
+		}
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"rbac/internal/domain"
+	"rbac/internal/utils"
+	"strings"
+	"unicode/utf8"
+)
+
+// minSimilarityLength is the shortest username or email part the similarity
+// rule looks for, so short names do not rule out half the passwords
+const minSimilarityLength = 3
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// BreachedPasswordSource looks up passwords known from data breaches by
+// k-anonymity: it is only ever given the first 5 hex characters of the
+// password's SHA-1 hash, and returns the suffixes (the remaining 35
+// characters, uppercase) of every known hash in that range with their counts.
+type BreachedPasswordSource interface {
+	Range(ctx context.Context, prefix string) (map[string]int, error)
+}
+
+// PasswordPolicy decides which new passwords are acceptable and how they are hashed
+type PasswordPolicy struct {
+	minLength int
+	hashing   utils.PasswordHashParams
+	common    map[string]struct{}
+	breaches  BreachedPasswordSource
+}
+
+// NewPasswordPolicy creates a policy requiring minLength characters and
+// refusing common passwords and passwords resembling the account, hashing
+// with the given parameters. breaches may be nil to skip the breach lookup.
+func NewPasswordPolicy(minLength int, hashing utils.PasswordHashParams, breaches BreachedPasswordSource) *PasswordPolicy {
+	common := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			common[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return &PasswordPolicy{minLength: minLength, hashing: hashing, common: common, breaches: breaches}
+}
+
+// Hash hashes a password with the current parameters
+func (p *PasswordPolicy) Hash(password string) (string, error) {
+	return utils.HashPassword(password, p.hashing)
+}
+
+// NeedsRehash reports whether a stored hash is weaker than the current parameters
+func (p *PasswordPolicy) NeedsRehash(hash string) bool {
+	return utils.NeedsRehash(hash, p.hashing)
+}
+
+// Check returns a *PasswordPolicyError listing every rule password breaks, or
+// nil. username and email are those of the account the password is for.
+func (p *PasswordPolicy) Check(ctx context.Context, password, username, email string) error {
+	var violations []domain.PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, domain.PasswordViolation{Code: code, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		add(domain.PasswordTooShort, fmt.Sprintf("must be at least %d characters long", p.minLength))
+	}
+	if maxBytes := p.hashing.MaxPasswordBytes(); len(password) > maxBytes {
+		add(domain.PasswordTooLong, fmt.Sprintf("must be at most %d bytes long", maxBytes))
+	}
+	if p.isCommon(password) {
+		add(domain.PasswordCommon, "is too common")
+	}
+	if similarToAccount(password, username, email) {
+		add(domain.PasswordSimilar, "must not contain or resemble the username or email address")
+	}
+	if p.breaches != nil && len(violations) == 0 {
+		// The lookup only adds a safeguard; an unavailable source must not
+		// stop users from setting passwords
+		breached, err := p.isBreached(ctx, password)
+		if err != nil {
+			log.Printf("Breached password lookup failed: %v", err)
+		} else if breached {
+			add(domain.PasswordBreached, "has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isCommon reports whether the password, or the password with the digits and
+// symbols people tack on at the end removed, is on the common list
+func (p *PasswordPolicy) isCommon(password string) bool {
+	lower := strings.ToLower(password)
+	if _, ok := p.common[lower]; ok {
+		return true
+	}
+	trimmed := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	})
+	_, ok := p.common[trimmed]
+	return ok && trimmed != ""
+}
+
+// isBreached looks the password's SHA-1 hash up by its 5-character prefix
+func (p *PasswordPolicy) isBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := p.breaches.Range(ctx, hash[:5])
+	if err != nil {
+		return false, err
+	}
+	return suffixes[hash[5:]] > 0, nil
+}
+
+// similarToAccount reports whether the password contains the username or the
+// local part of the email address, forwards or backwards, or is contained in them
+func similarToAccount(password, username, email string) bool {
+	lower := strings.ToLower(password)
+	reversed := reverse(lower)
+	local := strings.ToLower(email)
+	if at := strings.LastIndex(local, "@"); at >= 0 {
+		local = local[:at]
+	}
+
+	for _, part := range []string{strings.ToLower(username), local} {
+		if utf8.RuneCountInString(part) < minSimilarityLength {
+			continue
+		}
+		if strings.Contains(lower, part) || strings.Contains(reversed, part) || strings.Contains(part, lower) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
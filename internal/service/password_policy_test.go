@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"rbac/internal/domain"
+	"rbac/internal/utils"
+	"strings"
+	"testing"
+)
+
+// fakeBreaches serves the k-anonymity ranges of a fixed list of passwords
+type fakeBreaches struct {
+	passwords []string
+	err       error
+	prefixes  []string // Every prefix asked for
+}
+
+func (f *fakeBreaches) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	f.prefixes = append(f.prefixes, prefix)
+	if f.err != nil {
+		return nil, f.err
+	}
+	suffixes := make(map[string]int)
+	for _, password := range f.passwords {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		if strings.HasPrefix(hash, prefix) {
+			suffixes[hash[5:]] = 3
+		}
+	}
+	return suffixes, nil
+}
+
+var testHashParams = utils.PasswordHashParams{Algorithm: utils.HashArgon2id, Argon2MemoryKiB: 64, Argon2Iterations: 1, Argon2Parallelism: 1}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	const breached = "tr0ub4dor&3-horse"
+	tests := []struct {
+		name     string
+		params   utils.PasswordHashParams
+		password string
+		want     []string
+	}{
+		{"acceptable", testHashParams, "violet-gravel-anchor", nil},
+		{"too short", testHashParams, "v1olet", []string{domain.PasswordTooShort}},
+		// Counted in characters, not bytes
+		{"multibyte at the minimum", testHashParams, "ñandú-sólido", nil},
+		{"too long for bcrypt", utils.PasswordHashParams{Algorithm: utils.HashBcrypt}, strings.Repeat("violet", 13), []string{domain.PasswordTooLong}},
+		{"long for argon2id", testHashParams, strings.Repeat("violet", 13), nil},
+		{"too long for argon2id", testHashParams, strings.Repeat("violet", 200), []string{domain.PasswordTooLong}},
+		{"common", testHashParams, "password", []string{domain.PasswordTooShort, domain.PasswordCommon}},
+		{"common in another case", testHashParams, "LetMeIn", []string{domain.PasswordTooShort, domain.PasswordCommon}},
+		{"common with a suffix", testHashParams, "Password123!", []string{domain.PasswordCommon}},
+		{"common with a prefix", testHashParams, "123!password", nil},
+		{"digits only is not trimmed away", testHashParams, "31415926535", nil},
+		{"contains the username", testHashParams, "my-jsmith-secret", []string{domain.PasswordSimilar}},
+		{"contains the reversed username", testHashParams, "my-htimsj-secret", []string{domain.PasswordSimilar}},
+		{"contains the email local part", testHashParams, "JohnSmith1984!", []string{domain.PasswordSimilar}},
+		{"contained in the username", testHashParams, "jsmith", []string{domain.PasswordTooShort, domain.PasswordSimilar}},
+		{"email domain is not checked", testHashParams, "example.com-2024", nil},
+		{"breached", testHashParams, breached, []string{domain.PasswordBreached}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewPasswordPolicy(10, tt.params, &fakeBreaches{passwords: []string{breached}})
+			err := policy.Check(context.Background(), tt.password, "jsmith", "johnsmith@example.com")
+			if got := violationCodes(t, err); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Check(%q) violations = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicySimilarityIgnoresShortNames(t *testing.T) {
+	policy := NewPasswordPolicy(10, testHashParams, nil)
+	// "al" is shorter than minSimilarityLength, so it may appear in the password
+	if err := policy.Check(context.Background(), "metal-crystal-walrus", "al", "al@example.com"); err != nil {
+		t.Errorf("Check = %v, want nil", err)
+	}
+}
+
+func TestPasswordPolicyBreachLookup(t *testing.T) {
+	const password = "violet-gravel-anchor"
+	sum := sha1.Sum([]byte(password))
+	wantPrefix := strings.ToUpper(hex.EncodeToString(sum[:]))[:5]
+
+	tests := []struct {
+		name        string
+		password    string
+		breaches    *fakeBreaches
+		want        []string
+		wantLookups int
+	}{
+		{"not breached", password, &fakeBreaches{}, nil, 1},
+		{"breached", password, &fakeBreaches{passwords: []string{password}}, []string{domain.PasswordBreached}, 1},
+		// An unavailable source lets the password through
+		{"source unavailable", password, &fakeBreaches{err: errors.New("unavailable")}, nil, 1},
+		// Passwords already refused are not looked up
+		{"already refused", "short", &fakeBreaches{passwords: []string{"short"}}, []string{domain.PasswordTooShort}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewPasswordPolicy(10, testHashParams, tt.breaches)
+			err := policy.Check(context.Background(), tt.password, "jsmith", "jsmith@example.com")
+			if got := violationCodes(t, err); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+			if len(tt.breaches.prefixes) != tt.wantLookups {
+				t.Fatalf("looked up %d ranges, want %d", len(tt.breaches.prefixes), tt.wantLookups)
+			}
+			// Only the prefix of the hash ever leaves the policy
+			for _, prefix := range tt.breaches.prefixes {
+				if prefix != wantPrefix {
+					t.Errorf("looked up prefix %q, want %q", prefix, wantPrefix)
+				}
+			}
+		})
+	}
+}
+
+// violationCodes returns the codes of a *PasswordPolicyError, or nil for a nil error
+func violationCodes(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Check = %v, want a *PasswordPolicyError", err)
+	}
+	codes := make([]string, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		codes[i] = v.Code
+	}
+	return codes
+}
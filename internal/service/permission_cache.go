@@ -0,0 +1,142 @@
+package service
+
+import (
+	"container/list"
+	"rbac/internal/domain"
+	"sync"
+	"time"
+)
+
+// PermissionCache is an in-process LRU cache of each user's grants per
+// organization, with a TTL as a backstop. Services that change role
+// assignments, role grants or role definitions invalidate it directly, so
+// revocations take effect immediately on this instance.
+//
+// A nil *PermissionCache is valid and caches nothing.
+type PermissionCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[permissionCacheKey]*list.Element
+	lru        *list.List // Front is most recently used
+	// generation changes on every invalidation; a load that started before an
+	// invalidation is not stored, so it cannot resurrect revoked grants
+	generation uint64
+}
+
+type permissionCacheKey struct {
+	userID int64
+	orgID  int64
+}
+
+type permissionCacheEntry struct {
+	key       permissionCacheKey
+	grants    []domain.Grant
+	expiresAt time.Time
+}
+
+// NewPermissionCache creates a cache holding at most maxEntries users for ttl each.
+// It returns nil (caching disabled) if ttl or maxEntries is not positive.
+func NewPermissionCache(ttl time.Duration, maxEntries int) *PermissionCache {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &PermissionCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[permissionCacheKey]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get returns the cached grants and the current generation. The generation must
+// be passed back to Set when the caller loads the grants itself after a miss.
+// The returned slice is shared and must not be modified.
+func (c *PermissionCache) Get(userID, orgID int64) ([]domain.Grant, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[permissionCacheKey{userID, orgID}]
+	if !ok {
+		return nil, c.generation, false
+	}
+	entry := el.Value.(*permissionCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(el)
+		return nil, c.generation, false
+	}
+	c.lru.MoveToFront(el)
+	return entry.grants, c.generation, true
+}
+
+// Set stores grants loaded at generation. It is a no-op if the cache was
+// invalidated since then. validUntil, when not nil, is when the grants stop
+// being accurate because an assignment starts or ends; the entry expires then
+// if that is sooner than the TTL.
+func (c *PermissionCache) Set(userID, orgID int64, grants []domain.Grant, generation uint64, validUntil *time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	if validUntil != nil && validUntil.Before(expiresAt) {
+		expiresAt = *validUntil
+	}
+	key := permissionCacheKey{userID, orgID}
+	entry := &permissionCacheEntry{key: key, grants: grants, expiresAt: expiresAt}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// InvalidateUser drops a user's grants in every organization.
+// Use it after changing the user's role assignments or group memberships.
+func (c *PermissionCache) InvalidateUser(userID int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key, el := range c.entries {
+		if key.userID == userID {
+			c.remove(el)
+		}
+	}
+}
+
+// InvalidateAll drops every entry. Use it after changing a role's grants, a
+// role's parent or a group's roles, which can affect any number of users.
+func (c *PermissionCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[permissionCacheKey]*list.Element)
+	c.lru.Init()
+}
+
+func (c *PermissionCache) remove(el *list.Element) {
+	entry := el.Value.(*permissionCacheEntry)
+	delete(c.entries, entry.key)
+	c.lru.Remove(el)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"rbac/internal/domain"
+	"testing"
+	"time"
+)
+
+var testGrants = []domain.Grant{{Permission: "read_product", Effect: domain.EffectAllow}}
+
+func TestPermissionCacheExpiry(t *testing.T) {
+	const ttl = time.Minute
+	tests := []struct {
+		name       string
+		validUntil time.Duration // 0 means nil
+		wantHit    bool
+		wantExpiry time.Duration // from now
+	}{
+		{"ttl without validity change", 0, true, ttl},
+		{"validity change before ttl", 10 * time.Second, true, 10 * time.Second},
+		{"validity change after ttl", time.Hour, true, ttl},
+		{"validity change already passed", -time.Second, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPermissionCache(ttl, 10)
+			_, generation, _ := c.Get(1, 1)
+			var validUntil *time.Time
+			if tt.validUntil != 0 {
+				at := time.Now().Add(tt.validUntil)
+				validUntil = &at
+			}
+			c.Set(1, 1, testGrants, generation, validUntil)
+
+			_, _, hit := c.Get(1, 1)
+			if hit != tt.wantHit {
+				t.Fatalf("Get hit = %v, want %v", hit, tt.wantHit)
+			}
+			if !hit {
+				return
+			}
+			entry := c.entries[permissionCacheKey{1, 1}].Value.(*permissionCacheEntry)
+			if d := time.Until(entry.expiresAt) - tt.wantExpiry; d > time.Second || d < -time.Second {
+				t.Errorf("entry expires in %v, want about %v", time.Until(entry.expiresAt), tt.wantExpiry)
+			}
+		})
+	}
+}
+
+func TestPermissionCacheGeneration(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *PermissionCache)
+		wantStored bool
+	}{
+		{"no invalidation", func(c *PermissionCache) {}, true},
+		{"same user invalidated", func(c *PermissionCache) { c.InvalidateUser(1) }, false},
+		// The generation is shared, so any invalidation discards loads in flight
+		{"other user invalidated", func(c *PermissionCache) { c.InvalidateUser(2) }, false},
+		{"everything invalidated", func(c *PermissionCache) { c.InvalidateAll() }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPermissionCache(time.Minute, 10)
+			_, generation, _ := c.Get(1, 1)
+			tt.invalidate(c)
+			c.Set(1, 1, testGrants, generation, nil)
+			if _, _, hit := c.Get(1, 1); hit != tt.wantStored {
+				t.Errorf("Get hit = %v, want %v", hit, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestPermissionCacheInvalidateUser(t *testing.T) {
+	c := NewPermissionCache(time.Minute, 10)
+	for _, key := range []permissionCacheKey{{1, 1}, {1, 2}, {2, 1}} {
+		_, generation, _ := c.Get(key.userID, key.orgID)
+		c.Set(key.userID, key.orgID, testGrants, generation, nil)
+	}
+	c.InvalidateUser(1)
+
+	tests := []struct {
+		userID, orgID int64
+		wantHit       bool
+	}{
+		{1, 1, false},
+		{1, 2, false},
+		{2, 1, true},
+	}
+	for _, tt := range tests {
+		if _, _, hit := c.Get(tt.userID, tt.orgID); hit != tt.wantHit {
+			t.Errorf("Get(%d, %d) hit = %v, want %v", tt.userID, tt.orgID, hit, tt.wantHit)
+		}
+	}
+}
+
+func TestPermissionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewPermissionCache(time.Minute, 2)
+	set := func(userID int64) {
+		_, generation, _ := c.Get(userID, 1)
+		c.Set(userID, 1, testGrants, generation, nil)
+	}
+	set(1)
+	set(2)
+	c.Get(1, 1) // 2 is now the least recently used
+	set(3)
+
+	tests := []struct {
+		userID  int64
+		wantHit bool
+	}{
+		{1, true},
+		{2, false},
+		{3, true},
+	}
+	for _, tt := range tests {
+		if _, _, hit := c.Get(tt.userID, 1); hit != tt.wantHit {
+			t.Errorf("Get(%d, 1) hit = %v, want %v", tt.userID, hit, tt.wantHit)
+		}
+	}
+}
+
+func TestDisabledPermissionCache(t *testing.T) {
+	tests := []struct {
+		ttl        time.Duration
+		maxEntries int
+	}{
+		{0, 10},
+		{time.Minute, 0},
+	}
+	for _, tt := range tests {
+		c := NewPermissionCache(tt.ttl, tt.maxEntries)
+		if c != nil {
+			t.Fatalf("NewPermissionCache(%v, %d) = %v, want nil", tt.ttl, tt.maxEntries, c)
+		}
+		c.Set(1, 1, testGrants, 0, nil)
+		if _, _, hit := c.Get(1, 1); hit {
+			t.Errorf("nil cache returned a hit")
+		}
+		c.InvalidateUser(1)
+		c.InvalidateAll()
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import "strings"
+
+const (
+	// permissionSeparator splits namespaced permission names, e.g. "product:create"
+	permissionSeparator = ":"
+	// permissionWildcard matches any value for a segment, e.g. "product:*" or "*:read"
+	permissionWildcard = "*"
+)
+
+// matchPermission reports whether a granted permission covers the required one.
+// Names are compared segment by segment, and a "*" segment in the grant matches
+// any value in that position. A grant of just "*" matches every permission.
+// Flat names such as "create_product" are single-segment names, so they keep
+// matching exactly as before.
+func matchPermission(granted, required string) bool {
+	if granted == required || granted == permissionWildcard {
+		return true
+	}
+	if !strings.Contains(granted, permissionWildcard) {
+		return false
+	}
+
+	grantedParts := strings.Split(granted, permissionSeparator)
+	requiredParts := strings.Split(required, permissionSeparator)
+	if len(grantedParts) != len(requiredParts) {
+		return false
+	}
+	for i, part := range grantedParts {
+		if part != permissionWildcard && part != requiredParts[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package service
+
+import "testing"
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{"create_product", "create_product", true},
+		{"create_product", "read_product", false},
+		{"product:create", "product:create", true},
+		{"product:create", "product:read", false},
+		{"*", "create_product", true},
+		{"*", "product:create", true},
+		{"product:*", "product:create", true},
+		{"product:*", "order:create", false},
+		{"*:read", "product:read", true},
+		{"*:read", "product:create", false},
+		{"*:*", "product:read", true},
+		{"product:*:read", "product:variant:read", true},
+		// A wildcard covers exactly one segment
+		{"product:*", "product:variant:read", false},
+		{"product:*", "product", false},
+		{"*:*", "create_product", false},
+		// Only whole segments are wildcards
+		{"prod*:read", "product:read", false},
+		// Wildcards in the required name are not special
+		{"product:create", "product:*", false},
+	}
+	for _, tt := range tests {
+		if got := matchPermission(tt.granted, tt.required); got != tt.want {
+			t.Errorf("matchPermission(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,18 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"rbac/internal/domain"
 	"rbac/internal/repository"
+	"strconv"
+)
+
+// Product listings are paginated; larger limits are clamped
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+	// maxProductNameLength matches products.name (VARCHAR(255))
+	maxProductNameLength = 255
 )
 
 type productService struct {
@@ -15,8 +25,13 @@ func NewProductService(productRepo repository.ProductRepository) ProductService
 	return &productService{productRepo: productRepo}
 }
 
-func (s *productService) CreateProduct(ctx context.Context, req domain.CreateProductRequest, userID int64) (*domain.Product, error) {
+func (s *productService) CreateProduct(ctx context.Context, req domain.CreateProductRequest, userID, orgID int64) (*domain.Product, error) {
+	if err := validateProduct(req.Name, req.Price); err != nil {
+		return nil, err
+	}
+
 	product := &domain.Product{
+		OrgID:           orgID,
 		Name:            req.Name,
 		Price:           req.Price,
 		CreatedByUserID: userID,
@@ -27,4 +42,116 @@ func (s *productService) CreateProduct(ctx context.Context, req domain.CreatePro
 	}
 
 	return product, nil
-}
\ No newline at end of file
+}
+
+// GetProduct returns a product of the active organization. Products of other
+// organizations are reported as repository.ErrNotFound.
+func (s *productService) GetProduct(ctx context.Context, id int64) (*domain.Product, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+
+	product, err := s.productRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.OrgID != orgID {
+		return nil, repository.ErrNotFound
+	}
+	return product, nil
+}
+
+// ListProducts returns one page of the active organization's products.
+// Pass the returned NextCursor back as filter.Cursor to fetch the next page.
+func (s *productService) ListProducts(ctx context.Context, filter domain.ProductFilter) (*domain.ProductPage, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+
+	afterID, err := decodeProductCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, newValidationError("min_price must not exceed max_price")
+	}
+	limit := filter.Limit
+	if limit < 1 {
+		limit = defaultProductPageSize
+	}
+	if limit > maxProductPageSize {
+		limit = maxProductPageSize
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	products, err := s.productRepo.List(ctx, orgID, filter, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.ProductPage{Products: products}
+	if len(products) > limit {
+		page.Products = products[:limit]
+		page.NextCursor = encodeProductCursor(page.Products[limit-1].ID)
+	}
+	return page, nil
+}
+
+// UpdateProduct replaces a product's name and price
+func (s *productService) UpdateProduct(ctx context.Context, id int64, req domain.UpdateProductRequest) (*domain.Product, error) {
+	if err := validateProduct(req.Name, req.Price); err != nil {
+		return nil, err
+	}
+	product, err := s.GetProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Name, product.Price = req.Name, req.Price
+	if err := s.productRepo.Update(ctx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *productService) DeleteProduct(ctx context.Context, id int64) error {
+	if _, err := s.GetProduct(ctx, id); err != nil {
+		return err
+	}
+	return s.productRepo.Delete(ctx, id)
+}
+
+func validateProduct(name string, price float64) error {
+	if name == "" {
+		return newValidationError("product name is required")
+	}
+	if len(name) > maxProductNameLength {
+		return newValidationError("product name must be at most 255 characters")
+	}
+	if price < 0 {
+		return newValidationError("price must not be negative")
+	}
+	return nil
+}
+
+// Cursors are opaque to clients: the last ID of the previous page, base64-encoded
+func encodeProductCursor(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+}
+
+func decodeProductCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, newValidationError("invalid cursor")
+	}
+	lastID, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || lastID < 0 {
+		return 0, newValidationError("invalid cursor")
+	}
+	return lastID, nil
+}
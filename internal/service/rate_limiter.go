@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rbac/internal/repository"
+	"time"
+)
+
+// ErrRateLimited is returned for a request refused by a RateLimiter
+var ErrRateLimited = errors.New("too many requests; try again later")
+
+// RateLimiter caps how many requests each subject can make in a window.
+// Requests are counted in the login failure store under the limiter's own
+// scope, so every instance enforces the same limit, and they are counted
+// before they are served, so parallel requests cannot all get in under it.
+type RateLimiter struct {
+	repo   repository.LoginFailureRepository
+	scope  string
+	limit  int
+	window time.Duration
+}
+
+// NewRateLimiter creates a limiter allowing limit requests per subject within
+// window. A limit of 0 disables it.
+func NewRateLimiter(repo repository.LoginFailureRepository, scope string, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{repo: repo, scope: scope, limit: limit, window: window}
+}
+
+// Allow counts a request by subject, failing with ErrRateLimited once subject
+// is over the limit. The count starts over only after a quiet period of one
+// window, so refused requests keep the subject blocked. An empty subject is
+// not tracked.
+func (l *RateLimiter) Allow(ctx context.Context, subject string) error {
+	if l.limit <= 0 || subject == "" {
+		return nil
+	}
+	now := time.Now()
+	record, err := l.repo.RecordFailure(ctx, l.scope, subject, now, now.Add(-l.window))
+	if err != nil {
+		return err
+	}
+	if record.Failures > l.limit {
+		return ErrRateLimited
+	}
+	return nil
+}
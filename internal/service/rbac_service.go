@@ -2,35 +2,489 @@ package service
 
 import (
 	"context"
+	"errors"
+	"log"
+	"rbac/internal/domain"
+	"rbac/internal/policy"
 	"rbac/internal/repository"
+	"sync"
+	"time"
 )
 
+// ResourceProduct is the resource type for products
+const ResourceProduct = "product"
+
+// ErrUnknownResourceType is returned when no ownership lookup exists for a resource type
+var ErrUnknownResourceType = errors.New("unknown resource type")
+
 type rbacService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	productRepo repository.ProductRepository
+	cache       *PermissionCache
+	conditions  sync.Map // condition source -> *policy.Expression
+	// unverifiedBlocked lists permission patterns refused to users whose
+	// email address is not verified
+	unverifiedBlocked []string
 }
 
-// NewRBACService creates a new RBACService
-func NewRBACService(userRepo repository.UserRepository) RBACService {
-	return &rbacService{userRepo: userRepo}
+// NewRBACService creates a new RBACService. cache may be nil to disable caching.
+// Permissions matching a pattern in unverifiedBlocked are refused to users
+// who have not verified their email address, whatever their grants.
+func NewRBACService(userRepo repository.UserRepository, productRepo repository.ProductRepository, cache *PermissionCache, unverifiedBlocked []string) RBACService {
+	return &rbacService{userRepo: userRepo, productRepo: productRepo, cache: cache, unverifiedBlocked: unverifiedBlocked}
 }
 
-// CheckPermission checks if a user has a specific permission
-func (s *rbacService) CheckPermission(ctx context.Context, userID int64, requiredPermission string) (bool, error) {
-	permissions, err := s.userRepo.GetUserPermissions(ctx, userID)
+// CheckPermission checks if a user has a specific permission.
+// Permissions inherited through parent roles are included by GetUserPermissions,
+// and wildcard grants are expanded by matchPermission. Only unscoped allows count
+// here; "own" and "team" grants need a resource, see CheckResourcePermission.
+// Only grants in the active organization of ctx count (see WithOrgID), and grant
+// conditions are evaluated against the request environment in ctx (see
+// policy.WithEnvironment). A matching deny always wins and is reported as
+// the decision's rule.
+func (s *rbacService) CheckPermission(ctx context.Context, userID int64, requiredPermission string) (domain.Decision, error) {
+	grants, err := s.loadGrants(ctx, userID)
 	if err != nil {
-		return false, err
+		return domain.Decision{}, err
+	}
+
+	return s.decide(ctx, userID, grants, requiredPermission, nil, nil)
+}
+
+// CheckPermissions checks several permissions at once with a single permission
+// load, using the same rules as CheckPermission. Duplicate names are checked once.
+func (s *rbacService) CheckPermissions(ctx context.Context, userID int64, permissions []string) (map[string]bool, error) {
+	grants, err := s.loadGrants(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Simple map-based lookup for O(1) average time complexity
-	permMap := make(map[string]struct{})
+	results := make(map[string]bool, len(permissions))
 	for _, p := range permissions {
-		permMap[p] = struct{}{}
+		if _, done := results[p]; done {
+			continue
+		}
+		decision, err := s.decide(ctx, userID, grants, p, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		results[p] = decision.Allowed
+	}
+	return results, nil
+}
+
+// CheckResourcePermission checks if a user has a permission on one specific resource.
+// An unscoped grant always applies; "own" and "team" grants are matched against the
+// resource's owner. Returns repository.ErrNotFound if the resource does not exist
+// or belongs to another organization.
+func (s *rbacService) CheckResourcePermission(ctx context.Context, userID int64, requiredPermission, resourceType string, resourceID int64) (domain.Decision, error) {
+	grants, err := s.loadGrants(ctx, userID)
+	if err != nil {
+		return domain.Decision{}, err
+	}
+
+	matched := false
+	for _, g := range grants {
+		if matchPermission(g.Permission, requiredPermission) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return domain.Decision{}, nil // Forbidden, no need to look the resource up
+	}
+
+	resource, err := s.loadResource(ctx, resourceType, resourceID)
+	if err != nil {
+		return domain.Decision{}, err
+	}
+
+	return s.decide(ctx, userID, grants, requiredPermission, resource, nil)
+}
+
+// CheckPlatformPermission checks a permission granted outside of any
+// organization. These guard what every tenant shares, such as role and
+// permission definitions, so roles held in an organization never count,
+// whoever assigned them. Wildcards and API key restrictions apply as in
+// CheckPermission; scopes, conditions and deny rules do not exist here.
+func (s *rbacService) CheckPlatformPermission(ctx context.Context, userID int64, requiredPermission string) (domain.Decision, error) {
+	if !apiKeyAllows(ctx, userID, requiredPermission) {
+		return domain.Decision{}, nil
+	}
+	names, err := s.userRepo.GetPlatformPermissions(ctx, userID)
+	if err != nil {
+		return domain.Decision{}, err
+	}
+	for _, name := range names {
+		if matchPermission(name, requiredPermission) {
+			return domain.Decision{Allowed: true}, nil
+		}
+	}
+	return domain.Decision{}, nil
+}
+
+// Explain runs the same evaluation as CheckPermission/CheckResourcePermission
+// but returns the full derivation: the user's effective roles, every grant that
+// matched the permission, and how each scope and condition evaluated.
+// An optional IP and time in req replace the caller's request environment.
+func (s *rbacService) Explain(ctx context.Context, req domain.ExplainRequest) (*domain.Explanation, error) {
+	env := policy.EnvironmentFrom(ctx)
+	if req.IP != "" {
+		env.IP = req.IP
+	}
+	if req.Time != nil {
+		env.Time = *req.Time
+	}
+	ctx = policy.WithEnvironment(ctx, env)
+
+	grants, err := s.loadGrants(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.GetEffectiveRoles(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource *resourceRef
+	if req.ResourceType != "" {
+		if resource, err = s.loadResource(ctx, req.ResourceType, req.ResourceID); err != nil {
+			return nil, err
+		}
+	}
+
+	explanation := &domain.Explanation{Roles: roles, Evaluations: []domain.GrantEvaluation{}}
+	decision, err := s.decide(ctx, req.UserID, grants, req.Permission, resource, explanation)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Decision = decision
+	explanation.Reason = decision.Reason()
+	return explanation, nil
+}
+
+// loadResource looks up a resource's owner, hiding resources of other organizations
+func (s *rbacService) loadResource(ctx context.Context, resourceType string, resourceID int64) (*resourceRef, error) {
+	owner, err := s.resourceOwner(ctx, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	if orgID, _ := OrgIDFromContext(ctx); owner.OrgID != orgID {
+		// Resources in other organizations are invisible, not just forbidden
+		return nil, repository.ErrNotFound
+	}
+	return &resourceRef{resourceType: resourceType, id: resourceID, owner: owner}, nil
+}
+
+// loadGrants returns the user's grants in the active organization (see WithOrgID),
+// from the permission cache when possible
+func (s *rbacService) loadGrants(ctx context.Context, userID int64) ([]domain.Grant, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+
+	grants, generation, ok := s.cache.Get(userID, orgID)
+	if ok {
+		return grants, nil
+	}
+	now := time.Now()
+	grants, err := s.userRepo.GetUserPermissions(ctx, userID, orgID, now)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		// The grants change when an assignment's validity window opens or closes
+		validUntil, err := s.userRepo.NextValidityChange(ctx, userID, orgID, now)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Set(userID, orgID, grants, generation, validUntil)
+	}
+	return grants, nil
+}
+
+// decide evaluates grants against the required permission.
+// Any applicable deny wins over every allow. Without a resource (resource == nil)
+// a scoped deny cannot be ruled out, so it is treated as applicable.
+// When trace is non-nil every matching grant is evaluated and recorded in it,
+// instead of stopping at the first deny.
+func (s *rbacService) decide(ctx context.Context, userID int64, grants []domain.Grant, requiredPermission string, resource *resourceRef, trace *domain.Explanation) (domain.Decision, error) {
+	if !apiKeyAllows(ctx, userID, requiredPermission) {
+		return domain.Decision{}, nil // Outside the permissions of the API key in use
+	}
+	eval := s.newEvaluation(userID, resource)
+	if blocked, err := s.blockedUnverified(ctx, eval, requiredPermission); err != nil || blocked {
+		return domain.Decision{Blocked: "email address is not verified"}, err
+	}
+	mfa := mfaVerified(ctx, userID)
+
+	var allow, deny *domain.Grant
+	for i := range grants {
+		g := &grants[i]
+		if !matchPermission(g.Permission, requiredPermission) {
+			continue
+		}
+
+		var step *domain.GrantEvaluation
+		if trace != nil {
+			step = &domain.GrantEvaluation{Grant: *g, Outcome: domain.OutcomeSkipped}
+		}
+
+		applies, err := eval.applies(ctx, g, step)
+		if err != nil {
+			return domain.Decision{}, err
+		}
+
+		switch {
+		case g.Effect == domain.EffectDeny && (applies || (resource == nil && g.Scope != domain.ScopeAny)):
+			if deny == nil {
+				deny = g
+			}
+			if step != nil {
+				step.Outcome = domain.OutcomeDeny
+			}
+		case g.Effect == domain.EffectAllow && applies && g.RequiresMFA && !mfa:
+			// Withheld from sessions without MFA; denies of such roles still apply
+			if step != nil {
+				step.MFARequired = true
+			}
+		case g.Effect == domain.EffectAllow && applies:
+			if allow == nil {
+				allow = g
+			}
+			if step != nil {
+				step.Outcome = domain.OutcomeAllow
+			}
+		}
+
+		if trace != nil {
+			trace.Evaluations = append(trace.Evaluations, *step)
+		} else if deny != nil {
+			break // Nothing can override a deny
+		}
+	}
+
+	switch {
+	case deny != nil:
+		return domain.Decision{Allowed: false, Rule: deny}, nil
+	case allow != nil:
+		return domain.Decision{Allowed: true, Rule: allow}, nil
+	default:
+		return domain.Decision{}, nil // Forbidden
+	}
+}
+
+// blockedUnverified reports whether the permission is withheld from the user
+// until they verify their email address. Service accounts have no mailbox and
+// are exempt.
+func (s *rbacService) blockedUnverified(ctx context.Context, eval *evaluation, requiredPermission string) (bool, error) {
+	matched := false
+	for _, p := range s.unverifiedBlocked {
+		if matchPermission(p, requiredPermission) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false, nil
+	}
+
+	user, err := eval.loadUser(ctx)
+	if err != nil {
+		return false, err
 	}
+	return !user.IsServiceAccount && user.EmailVerifiedAt == nil, nil
+}
+
+// resourceRef identifies the resource a check is made against, with its owner
+type resourceRef struct {
+	resourceType string
+	id           int64
+	owner        *domain.ResourceOwner
+}
+
+// evaluation carries the facts a single permission check needs.
+// The user and the resource attributes are only loaded when a grant needs them.
+type evaluation struct {
+	svc      *rbacService
+	userID   int64
+	resource *resourceRef
+	user     *domain.User
+	attrs    policy.Attributes
+}
 
-	// Check if the required permission exists in the user's permissions
-	if _, ok := permMap[requiredPermission]; ok {
+func (s *rbacService) newEvaluation(userID int64, resource *resourceRef) *evaluation {
+	return &evaluation{svc: s, userID: userID, resource: resource}
+}
+
+// applies reports whether a grant's scope and condition hold for this check.
+// If step is non-nil the intermediate results are recorded in it.
+func (e *evaluation) applies(ctx context.Context, g *domain.Grant, step *domain.GrantEvaluation) (bool, error) {
+	ok, err := e.scopeApplies(ctx, g.Scope)
+	if err != nil {
+		return false, err
+	}
+	if step != nil {
+		step.ScopeApplies = ok
+	}
+	if !ok {
+		return false, nil
+	}
+	if g.Condition == "" {
 		return true, nil
 	}
+	return e.conditionHolds(ctx, g, step)
+}
 
-	return false, nil // Forbidden
-}
\ No newline at end of file
+func (e *evaluation) scopeApplies(ctx context.Context, scope domain.PermissionScope) (bool, error) {
+	switch scope {
+	case domain.ScopeAny:
+		return true, nil
+	case domain.ScopeOwn:
+		return e.resource != nil && e.resource.owner.OwnerID == e.userID, nil
+	case domain.ScopeTeam:
+		if e.resource == nil || e.resource.owner.TeamID == nil {
+			return false, nil
+		}
+		user, err := e.loadUser(ctx)
+		if err != nil {
+			return false, err
+		}
+		return user.TeamID != nil && *user.TeamID == *e.resource.owner.TeamID, nil
+	default:
+		return false, nil
+	}
+}
+
+// conditionHolds evaluates a grant's condition. A condition that fails to compile
+// or references a missing attribute fails closed: the grant is skipped for an
+// allow, and applied for a deny.
+func (e *evaluation) conditionHolds(ctx context.Context, g *domain.Grant, step *domain.GrantEvaluation) (bool, error) {
+	expr, err := e.svc.compileCondition(g.Condition)
+	if err == nil {
+		var attrs policy.Attributes
+		attrs, err = e.attributes(ctx)
+		if err != nil {
+			return false, err
+		}
+		var ok bool
+		if ok, err = expr.Evaluate(attrs); err == nil {
+			if step != nil {
+				step.ConditionResult = &ok
+			}
+			return ok, nil
+		}
+	}
+
+	log.Printf("Condition on %s could not be evaluated: %v", g, err)
+	if step != nil {
+		step.ConditionError = err.Error()
+	}
+	return g.Effect == domain.EffectDeny, nil
+}
+
+func (e *evaluation) loadUser(ctx context.Context) (*domain.User, error) {
+	if e.user == nil {
+		user, err := e.svc.userRepo.FindByID(ctx, e.userID)
+		if err != nil {
+			return nil, err
+		}
+		e.user = user
+	}
+	return e.user, nil
+}
+
+// attributes builds the subject, resource and env attributes for conditions
+func (e *evaluation) attributes(ctx context.Context) (policy.Attributes, error) {
+	if e.attrs != nil {
+		return e.attrs, nil
+	}
+
+	user, err := e.loadUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attrs := policy.Attributes{}
+	attrs.Set(policy.NamespaceSubject, "id", user.ID)
+	attrs.Set(policy.NamespaceSubject, "username", user.Username)
+	attrs.Set(policy.NamespaceSubject, "email", user.Email)
+	attrs.Set(policy.NamespaceSubject, "email_verified", user.EmailVerifiedAt != nil)
+	if user.TeamID != nil {
+		attrs.Set(policy.NamespaceSubject, "team_id", *user.TeamID)
+	}
+	if orgID, ok := OrgIDFromContext(ctx); ok {
+		attrs.Set(policy.NamespaceSubject, "org_id", orgID)
+	}
+
+	attrs[policy.NamespaceEnv] = policy.EnvironmentFrom(ctx).Attributes()
+
+	if e.resource != nil {
+		resourceAttrs, err := e.svc.resourceAttributes(ctx, e.resource.resourceType, e.resource.id)
+		if err != nil {
+			return nil, err
+		}
+		resourceAttrs["type"] = e.resource.resourceType
+		resourceAttrs["id"] = e.resource.id
+		resourceAttrs["org_id"] = e.resource.owner.OrgID
+		resourceAttrs["owner_id"] = e.resource.owner.OwnerID
+		if e.resource.owner.TeamID != nil {
+			resourceAttrs["team_id"] = *e.resource.owner.TeamID
+		}
+		attrs[policy.NamespaceResource] = resourceAttrs
+	}
+
+	e.attrs = attrs
+	return attrs, nil
+}
+
+// compileCondition parses a condition once and caches the result
+func (s *rbacService) compileCondition(source string) (*policy.Expression, error) {
+	if cached, ok := s.conditions.Load(source); ok {
+		return cached.(*policy.Expression), nil
+	}
+	expr, err := policy.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	s.conditions.Store(source, expr)
+	return expr, nil
+}
+
+// resourceOwner looks up who owns a resource of the given type
+func (s *rbacService) resourceOwner(ctx context.Context, resourceType string, resourceID int64) (*domain.ResourceOwner, error) {
+	switch resourceType {
+	case ResourceProduct:
+		return s.productRepo.FindOwner(ctx, resourceID)
+	default:
+		return nil, ErrUnknownResourceType
+	}
+}
+
+// resourceAttributes loads the attributes conditions can reference as resource.*
+func (s *rbacService) resourceAttributes(ctx context.Context, resourceType string, resourceID int64) (map[string]interface{}, error) {
+	switch resourceType {
+	case ResourceProduct:
+		product, err := s.productRepo.FindByID(ctx, resourceID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"name":       product.Name,
+			"price":      product.Price,
+			"created_at": product.CreatedAt.Unix(),
+		}, nil
+	default:
+		return nil, ErrUnknownResourceType
+	}
+}
+
+// GetEffectiveRoles lists the roles a user holds in the active organization and
+// where each one came from: a direct assignment, a group, or inheritance
+func (s *rbacService) GetEffectiveRoles(ctx context.Context, userID int64) ([]domain.EffectiveRole, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+	return s.userRepo.GetEffectiveRoles(ctx, userID, orgID, time.Now())
+}
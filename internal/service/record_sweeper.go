@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"log"
+	"rbac/internal/repository"
+	"time"
+)
+
+// RecordSweeper periodically deletes rows that only matter for a while: used
+// action token IDs past the token's expiry, expired MFA challenges, and login
+// failure counts that have started over anyway. None of them affect any
+// decision once stale; the sweeper keeps the tables from growing without bound.
+type RecordSweeper struct {
+	actionRepo    repository.ActionTokenRepository
+	challengeRepo repository.MFAChallengeRepository
+	failureRepo   repository.LoginFailureRepository
+	// failureRetention is the longest window any throttle or rate limit counts over
+	failureRetention time.Duration
+	interval         time.Duration
+}
+
+// NewRecordSweeper creates a sweeper that runs every interval. Login failure
+// rows are kept for failureRetention after their last failure.
+func NewRecordSweeper(actionRepo repository.ActionTokenRepository, challengeRepo repository.MFAChallengeRepository, failureRepo repository.LoginFailureRepository, failureRetention, interval time.Duration) *RecordSweeper {
+	return &RecordSweeper{
+		actionRepo:       actionRepo,
+		challengeRepo:    challengeRepo,
+		failureRepo:      failureRepo,
+		failureRetention: failureRetention,
+		interval:         interval,
+	}
+}
+
+// Run sweeps until ctx is cancelled
+func (s *RecordSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.Sweep(ctx); err != nil {
+			log.Printf("Record sweep failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Record sweep removed %d stale rows", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes every stale row and returns how many were deleted
+func (s *RecordSweeper) Sweep(ctx context.Context) (int64, error) {
+	actions, err := s.actionRepo.DeleteExpired(ctx)
+	if err != nil {
+		return 0, err
+	}
+	challenges, err := s.challengeRepo.DeleteExpired(ctx)
+	if err != nil {
+		return actions, err
+	}
+	failures, err := s.failureRepo.DeleteStale(ctx, time.Now().Add(-s.failureRetention))
+	if err != nil {
+		return actions + challenges, err
+	}
+	return actions + challenges + failures, nil
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"rbac/internal/domain"
+	"rbac/internal/policy"
+	"rbac/internal/repository"
+	"regexp"
+)
+
+// Role and permission names share the column size of the schema (VARCHAR(50))
+const maxNameLength = 50
+
+var (
+	// roleNamePattern: lower-case words, e.g. "admin" or "support_agent"
+	roleNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	// permissionNamePattern: flat ("create_product") or namespaced ("product:create"),
+	// with "*" allowed as a whole segment ("product:*", "*:read")
+	permissionNamePattern = regexp.MustCompile(`^([a-z0-9_]+|\*)(:([a-z0-9_]+|\*))*$`)
+)
+
+type roleService struct {
+	roleRepo repository.RoleRepository
+	cache    *PermissionCache
+}
+
+// NewRoleService creates a new RoleService
+func NewRoleService(roleRepo repository.RoleRepository, cache *PermissionCache) RoleService {
+	return &roleService{roleRepo: roleRepo, cache: cache}
+}
+
+// --- Roles ---
+
+func (s *roleService) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	return s.roleRepo.List(ctx)
+}
+
+func (s *roleService) GetRole(ctx context.Context, id int64) (*domain.Role, error) {
+	return s.roleRepo.FindByID(ctx, id)
+}
+
+func (s *roleService) CreateRole(ctx context.Context, req domain.RoleRequest) (*domain.Role, error) {
+	if err := validateName("role", req.Name, roleNamePattern); err != nil {
+		return nil, err
+	}
+	if req.ParentID != nil {
+		if _, err := s.roleRepo.FindByID(ctx, *req.ParentID); err != nil {
+			if err == repository.ErrNotFound {
+				return nil, newValidationError("parent role does not exist")
+			}
+			return nil, err
+		}
+	}
+
+	// A new role has no children yet, so no parent can create a cycle
+	role := &domain.Role{Name: req.Name, ParentID: req.ParentID, RequiresMFA: req.RequiresMFA}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole renames a role and sets its parent and MFA requirement
+func (s *roleService) UpdateRole(ctx context.Context, id int64, req domain.RoleRequest) (*domain.Role, error) {
+	if err := validateName("role", req.Name, roleNamePattern); err != nil {
+		return nil, err
+	}
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Refuse a cycle before changing anything, so a failed update changes nothing
+	if err := s.checkParent(ctx, id, req.ParentID); err != nil {
+		return nil, err
+	}
+
+	if role.Name != req.Name {
+		if err := s.roleRepo.Rename(ctx, id, req.Name); err != nil {
+			return nil, err
+		}
+		role.Name = req.Name
+		// Cached grants carry the role name
+		s.cache.InvalidateAll()
+	}
+	if role.RequiresMFA != req.RequiresMFA {
+		if err := s.roleRepo.SetRequiresMFA(ctx, id, req.RequiresMFA); err != nil {
+			return nil, err
+		}
+		role.RequiresMFA = req.RequiresMFA
+		// Cached grants carry the requirement
+		s.cache.InvalidateAll()
+	}
+	if err := s.setParent(ctx, id, req.ParentID); err != nil {
+		return nil, err
+	}
+	role.ParentID = req.ParentID
+	return role, nil
+}
+
+func (s *roleService) DeleteRole(ctx context.Context, id int64) error {
+	if err := s.roleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	// Assignments cascade away and child roles lose their parent
+	s.cache.InvalidateAll()
+	return nil
+}
+
+// SetRoleParent makes roleID inherit from parentID, or clears the parent when parentID is nil.
+// It returns repository.ErrRoleCycle if roleID is already an ancestor of parentID.
+func (s *roleService) SetRoleParent(ctx context.Context, roleID int64, parentID *int64) error {
+	if err := s.checkParent(ctx, roleID, parentID); err != nil {
+		return err
+	}
+	return s.setParent(ctx, roleID, parentID)
+}
+
+// checkParent returns repository.ErrRoleCycle if roleID is parentID or one of its ancestors
+func (s *roleService) checkParent(ctx context.Context, roleID int64, parentID *int64) error {
+	visited := map[int64]struct{}{}
+	for id := parentID; id != nil; {
+		if *id == roleID {
+			return repository.ErrRoleCycle
+		}
+		if _, seen := visited[*id]; seen {
+			// The existing hierarchy already loops; refuse to build on it
+			return repository.ErrRoleCycle
+		}
+		visited[*id] = struct{}{}
+
+		ancestor, err := s.roleRepo.FindByID(ctx, *id)
+		if err != nil {
+			return err
+		}
+		id = ancestor.ParentID
+	}
+	return nil
+}
+
+// setParent stores a parent already accepted by checkParent
+func (s *roleService) setParent(ctx context.Context, roleID int64, parentID *int64) error {
+	if err := s.roleRepo.SetParent(ctx, roleID, parentID); err != nil {
+		return err
+	}
+	// Every holder of roleID (or a descendant) may have gained or lost grants
+	s.cache.InvalidateAll()
+	return nil
+}
+
+// --- Permissions ---
+
+func (s *roleService) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	return s.roleRepo.ListPermissions(ctx)
+}
+
+func (s *roleService) GetPermission(ctx context.Context, id int64) (*domain.Permission, error) {
+	return s.roleRepo.FindPermissionByID(ctx, id)
+}
+
+func (s *roleService) CreatePermission(ctx context.Context, req domain.PermissionRequest) (*domain.Permission, error) {
+	if err := validateName("permission", req.Name, permissionNamePattern); err != nil {
+		return nil, err
+	}
+
+	perm := &domain.Permission{Name: req.Name, Description: req.Description}
+	if err := s.roleRepo.CreatePermission(ctx, perm); err != nil {
+		return nil, err
+	}
+	return perm, nil
+}
+
+func (s *roleService) UpdatePermission(ctx context.Context, id int64, req domain.PermissionRequest) (*domain.Permission, error) {
+	if err := validateName("permission", req.Name, permissionNamePattern); err != nil {
+		return nil, err
+	}
+	perm, err := s.roleRepo.FindPermissionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := perm.Name != req.Name
+	perm.Name, perm.Description = req.Name, req.Description
+	if err := s.roleRepo.UpdatePermission(ctx, perm); err != nil {
+		return nil, err
+	}
+	if renamed {
+		s.cache.InvalidateAll()
+	}
+	return perm, nil
+}
+
+func (s *roleService) DeletePermission(ctx context.Context, id int64) error {
+	if err := s.roleRepo.DeletePermission(ctx, id); err != nil {
+		return err
+	}
+	s.cache.InvalidateAll()
+	return nil
+}
+
+// --- Role grants ---
+
+func (s *roleService) ListRoleGrants(ctx context.Context, roleID int64) ([]domain.RoleGrant, error) {
+	if _, err := s.roleRepo.FindByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.ListGrants(ctx, roleID)
+}
+
+// GrantPermission attaches a permission to a role. Granting a permission the
+// role already has replaces its scope, effect and condition.
+func (s *roleService) GrantPermission(ctx context.Context, roleID int64, req domain.GrantPermissionRequest) (*domain.RoleGrant, error) {
+	grant := domain.RoleGrant{
+		PermissionID: req.PermissionID,
+		Scope:        req.Scope,
+		Effect:       req.Effect,
+		Condition:    req.Condition,
+	}
+	if grant.Scope == "" {
+		grant.Scope = domain.ScopeAny
+	}
+	if grant.Effect == "" {
+		grant.Effect = domain.EffectAllow
+	}
+
+	switch grant.Scope {
+	case domain.ScopeAny, domain.ScopeOwn, domain.ScopeTeam:
+	default:
+		return nil, newValidationError(fmt.Sprintf("unknown scope %q", grant.Scope))
+	}
+	switch grant.Effect {
+	case domain.EffectAllow, domain.EffectDeny:
+	default:
+		return nil, newValidationError(fmt.Sprintf("unknown effect %q", grant.Effect))
+	}
+	if grant.Condition != "" {
+		// Reject conditions that would only fail later, at check time
+		if _, err := policy.Compile(grant.Condition); err != nil {
+			return nil, newValidationError(err.Error())
+		}
+	}
+
+	if _, err := s.roleRepo.FindByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+	perm, err := s.roleRepo.FindPermissionByID(ctx, req.PermissionID)
+	if err != nil {
+		return nil, err
+	}
+	grant.Permission = perm.Name
+
+	if err := s.roleRepo.GrantPermission(ctx, roleID, grant); err != nil {
+		return nil, err
+	}
+	s.cache.InvalidateAll()
+	return &grant, nil
+}
+
+func (s *roleService) RevokePermission(ctx context.Context, roleID, permissionID int64) error {
+	if err := s.roleRepo.RevokePermission(ctx, roleID, permissionID); err != nil {
+		return err
+	}
+	s.cache.InvalidateAll()
+	return nil
+}
+
+func validateName(kind, name string, pattern *regexp.Regexp) error {
+	if name == "" {
+		return newValidationError(kind + " name is required")
+	}
+	if len(name) > maxNameLength {
+		return newValidationError(fmt.Sprintf("%s name must be at most %d characters", kind, maxNameLength))
+	}
+	if !pattern.MatchString(name) {
+		return newValidationError(fmt.Sprintf("%s name %q has invalid characters", kind, name))
+	}
+	return nil
+}
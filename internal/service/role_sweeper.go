@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"log"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"time"
+)
+
+// sweepBatchSize bounds how many expired assignments one sweep pass loads
+const sweepBatchSize = 500
+
+// RoleExpirySweeper periodically deletes expired role assignments and records
+// an audit event for each one. Expired rows are already ignored by permission
+// checks; the sweeper keeps the table clean and makes the lapse visible.
+type RoleExpirySweeper struct {
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
+	cache     *PermissionCache
+	interval  time.Duration
+}
+
+// NewRoleExpirySweeper creates a sweeper that runs every interval
+func NewRoleExpirySweeper(userRepo repository.UserRepository, auditRepo repository.AuditRepository, cache *PermissionCache, interval time.Duration) *RoleExpirySweeper {
+	return &RoleExpirySweeper{userRepo: userRepo, auditRepo: auditRepo, cache: cache, interval: interval}
+}
+
+// Run sweeps until ctx is cancelled
+func (s *RoleExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.Sweep(ctx); err != nil {
+			log.Printf("Role expiry sweep failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Role expiry sweep removed %d expired assignments", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep removes every assignment that has expired by now and returns how many were removed
+func (s *RoleExpirySweeper) Sweep(ctx context.Context) (int, error) {
+	removed := 0
+	for {
+		now := time.Now()
+		expired, err := s.userRepo.ListExpiredRoles(ctx, now, sweepBatchSize)
+		if err != nil {
+			return removed, err
+		}
+
+		batchRemoved := 0
+		for _, a := range expired {
+			deleted, err := s.userRepo.DeleteExpiredRole(ctx, a, now)
+			if err != nil {
+				return removed, err
+			}
+			if !deleted {
+				continue // Extended since it was listed
+			}
+			batchRemoved++
+			s.cache.InvalidateUser(a.UserID)
+			s.audit(ctx, a)
+		}
+		removed += batchRemoved
+
+		// A short batch means we are done; a batch with nothing deleted would loop forever
+		if len(expired) < sweepBatchSize || batchRemoved == 0 {
+			return removed, nil
+		}
+	}
+}
+
+func (s *RoleExpirySweeper) audit(ctx context.Context, a domain.RoleAssignment) {
+	userID, orgID := a.UserID, a.OrgID
+	event := &domain.AuditEvent{
+		Type:   domain.AuditRoleExpired,
+		UserID: &userID,
+		OrgID:  &orgID,
+		Details: map[string]interface{}{
+			"role_id":     a.RoleID,
+			"valid_from":  a.ValidFrom,
+			"valid_until": a.ValidUntil,
+		},
+	}
+	if err := s.auditRepo.Record(ctx, event); err != nil {
+		// The assignment is already gone; losing the audit row must not stop the sweep
+		log.Printf("Failed to record %s for user %d role %d: %v", event.Type, a.UserID, a.RoleID, err)
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"rbac/internal/utils"
+	"regexp"
+	"time"
+)
+
+// serviceAccountNamePattern: lower-case words joined by "-", "_" or ".", e.g. "billing-exporter"
+var serviceAccountNamePattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+
+type serviceAccountService struct {
+	userRepo    repository.UserRepository
+	accountRepo repository.ServiceAccountRepository
+	orgRepo     repository.OrganizationRepository
+	auditRepo   repository.AuditRepository
+	tx          repository.Transactor
+	cache       *PermissionCache
+	statusCache *UserStatusCache
+	keys        *KeyManager
+	accessTTL   time.Duration
+}
+
+// NewServiceAccountService creates a new ServiceAccountService
+func NewServiceAccountService(userRepo repository.UserRepository, accountRepo repository.ServiceAccountRepository, orgRepo repository.OrganizationRepository, auditRepo repository.AuditRepository, tx repository.Transactor, cache *PermissionCache, statusCache *UserStatusCache, keys *KeyManager, accessTTL time.Duration) ServiceAccountService {
+	return &serviceAccountService{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+		orgRepo:     orgRepo,
+		auditRepo:   auditRepo,
+		tx:          tx,
+		cache:       cache,
+		statusCache: statusCache,
+		keys:        keys,
+		accessTTL:   accessTTL,
+	}
+}
+
+// Create adds a service account to the active organization. It starts with no
+// roles; assign them like any user's. The secret is only returned here.
+func (s *serviceAccountService) Create(ctx context.Context, actorID int64, req domain.CreateServiceAccountRequest) (*domain.ServiceAccountCredentials, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+	if err := validateName("service account", req.Name, serviceAccountNamePattern); err != nil {
+		return nil, err
+	}
+
+	clientID, err := utils.RandomID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// No password: the empty hash never matches, and Login refuses service accounts anyway
+	user := &domain.User{Username: req.Name, IsServiceAccount: true}
+	account := &domain.ServiceAccount{
+		OrgID:       orgID,
+		Name:        user.Username,
+		ClientID:    clientID,
+		SecretHash:  utils.HashToken(secret),
+		Description: req.Description,
+		CreatedBy:   &actorID,
+		CreatedAt:   time.Now(),
+	}
+	// A failure part way must not leave a user behind that holds the name
+	err = s.tx.WithinTx(ctx, func(tx repository.TxRepositories) error {
+		if err := tx.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		if err := tx.Organizations.AddMember(ctx, orgID, user.ID); err != nil {
+			return err
+		}
+		account.UserID = user.ID
+		return tx.ServiceAccounts.Create(ctx, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, domain.AuditServiceAccountCreated, actorID, account); err != nil {
+		return nil, err
+	}
+	return &domain.ServiceAccountCredentials{ServiceAccount: *account, ClientSecret: secret}, nil
+}
+
+// List returns the service accounts of the active organization
+func (s *serviceAccountService) List(ctx context.Context) ([]domain.ServiceAccount, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+	return s.accountRepo.ListByOrg(ctx, orgID)
+}
+
+// RotateSecret replaces the secret and revokes every token issued with the old one
+func (s *serviceAccountService) RotateSecret(ctx context.Context, actorID, userID int64) (*domain.ServiceAccountCredentials, error) {
+	account, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	account.SecretHash = utils.HashToken(secret)
+	if err := s.accountRepo.UpdateSecret(ctx, userID, account.SecretHash); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.RevokeSessions(ctx, userID, time.Now()); err != nil {
+		return nil, err
+	}
+	s.statusCache.Invalidate(userID)
+
+	if err := s.audit(ctx, domain.AuditServiceAccountRotated, actorID, account); err != nil {
+		return nil, err
+	}
+	return &domain.ServiceAccountCredentials{ServiceAccount: *account, ClientSecret: secret}, nil
+}
+
+// Delete removes a service account; its outstanding tokens stop working at once
+func (s *serviceAccountService) Delete(ctx context.Context, actorID, userID int64) error {
+	account, err := s.find(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.accountRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	s.statusCache.Invalidate(userID)
+	s.cache.InvalidateUser(userID)
+
+	return s.audit(ctx, domain.AuditServiceAccountDeleted, actorID, account)
+}
+
+// IssueToken implements the client_credentials grant. The access token has the
+// same form as a user's, so AuthMiddleware and RBACMiddleware treat it alike.
+func (s *serviceAccountService) IssueToken(ctx context.Context, clientID, clientSecret string) (*domain.TokenResponse, error) {
+	account, err := s.accountRepo.FindByClientID(ctx, clientID)
+	if err == repository.ErrNotFound {
+		return nil, newOAuthError("invalid_client", "client authentication failed")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(account.SecretHash)) != 1 {
+		return nil, newOAuthError("invalid_client", "client authentication failed")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, account.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, newOAuthError("invalid_client", ErrAccountDeactivated.Error())
+	}
+
+	key, err := s.keys.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+	token, err := utils.GenerateToken(account.UserID, account.OrgID, nil, key, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.accessTTL / time.Second),
+	}, nil
+}
+
+// find returns a service account of the active organization, or repository.ErrNotFound
+func (s *serviceAccountService) find(ctx context.Context, userID int64) (*domain.ServiceAccount, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+	account, err := s.accountRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account.OrgID != orgID {
+		return nil, repository.ErrNotFound
+	}
+	return account, nil
+}
+
+func (s *serviceAccountService) audit(ctx context.Context, eventType string, actorID int64, account *domain.ServiceAccount) error {
+	event := &domain.AuditEvent{
+		Type:        eventType,
+		ActorUserID: &actorID,
+		UserID:      &account.UserID,
+		OrgID:       &account.OrgID,
+		Details:     map[string]interface{}{"client_id": account.ClientID},
+	}
+	return s.auditRepo.Record(ctx, event)
+}
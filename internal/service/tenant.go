@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rbac/internal/repository"
+)
+
+// ErrNoActiveOrg is returned when a check has no organization to evaluate grants in
+var ErrNoActiveOrg = errors.New("no active organization")
+
+// ErrNotOrgMember is returned when a user tries to act in an organization they do not belong to
+var ErrNotOrgMember = errors.New("user is not a member of this organization")
+
+// ErrNoOrganization is returned when a user who belongs to no organization signs in
+var ErrNoOrganization = errors.New("user does not belong to any organization")
+
+type orgIDKey struct{}
+
+// WithOrgID sets the active organization for the request.
+// Permission checks only evaluate role assignments made in this organization.
+func WithOrgID(ctx context.Context, orgID int64) context.Context {
+	return context.WithValue(ctx, orgIDKey{}, orgID)
+}
+
+// OrgIDFromContext returns the active organization set by WithOrgID
+func OrgIDFromContext(ctx context.Context) (int64, bool) {
+	orgID, ok := ctx.Value(orgIDKey{}).(int64)
+	return orgID, ok && orgID != 0
+}
+
+// resolveLoginOrg picks the organization a new session starts in: the requested
+// one if the user belongs to it, otherwise the user's oldest membership
+func resolveLoginOrg(ctx context.Context, orgRepo repository.OrganizationRepository, userID, requested int64) (int64, error) {
+	if requested != 0 {
+		member, err := orgRepo.IsMember(ctx, requested, userID)
+		if err != nil {
+			return 0, err
+		}
+		if !member {
+			return 0, ErrNotOrgMember
+		}
+		return requested, nil
+	}
+
+	orgs, err := orgRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if len(orgs) == 0 {
+		return 0, ErrNoOrganization
+	}
+	return orgs[0].ID, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"log"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"sync"
+	"time"
+)
+
+// syncOverlap re-reads a little of the previous sync window, so revocations
+// committed while the last sync ran (or stamped by a skewed DB clock) are not missed
+const syncOverlap = 10 * time.Second
+
+// TokenRevocationList keeps the IDs of revoked, unexpired access tokens in
+// memory, so checking a token costs a map lookup instead of a query. Revocations
+// made on this instance apply at once; Run polls the store for revocations made
+// by other instances.
+type TokenRevocationList struct {
+	repo     repository.TokenRevocationRepository
+	interval time.Duration
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time // jti -> token expiry
+	lastSync time.Time
+}
+
+// NewTokenRevocationList creates a list that syncs with repo every interval
+func NewTokenRevocationList(repo repository.TokenRevocationRepository, interval time.Duration) *TokenRevocationList {
+	return &TokenRevocationList{repo: repo, interval: interval, revoked: make(map[string]time.Time)}
+}
+
+// IsRevoked reports whether the token with this jti has been revoked
+func (l *TokenRevocationList) IsRevoked(jti string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.revoked[jti]
+	return ok
+}
+
+// Revoke stores a revocation and applies it locally
+func (l *TokenRevocationList) Revoke(ctx context.Context, token domain.RevokedToken) error {
+	if err := l.repo.Revoke(ctx, token); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.revoked[token.JTI] = token.ExpiresAt
+	l.mu.Unlock()
+	return nil
+}
+
+// Sync loads revocations recorded since the previous sync (all unexpired ones
+// the first time) and forgets tokens that have expired anyway
+func (l *TokenRevocationList) Sync(ctx context.Context) error {
+	l.mu.RLock()
+	since := l.lastSync
+	l.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-syncOverlap)
+	}
+
+	started := time.Now()
+	tokens, err := l.repo.ListSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, t := range tokens {
+		l.revoked[t.JTI] = t.ExpiresAt
+	}
+	for jti, expiresAt := range l.revoked {
+		if started.After(expiresAt) {
+			delete(l.revoked, jti)
+		}
+	}
+	l.lastSync = started
+	return nil
+}
+
+// Run syncs until ctx is cancelled, and purges expired revocations from the store
+func (l *TokenRevocationList) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.Sync(ctx); err != nil {
+			log.Printf("Token revocation sync failed: %v", err)
+		}
+		if _, err := l.repo.DeleteExpired(ctx); err != nil {
+			log.Printf("Failed to purge expired token revocations: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rbac/internal/domain"
+	"rbac/internal/repository"
+	"time"
+)
+
+// ErrInvalidValidity is returned when a role assignment window ends before it starts
+var ErrInvalidValidity = errors.New("valid_until must be after valid_from")
+
+// User listings are paginated; larger page sizes are clamped
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+type userService struct {
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	orgRepo     repository.OrganizationRepository
+	auditRepo   repository.AuditRepository
+	refreshRepo repository.RefreshTokenRepository
+	apiKeyRepo  repository.APIKeyRepository
+	cache       *PermissionCache
+	statusCache *UserStatusCache
+	keyCache    *APIKeyCache
+	throttle    *LoginThrottle
+}
+
+// NewUserService creates a new UserService
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, orgRepo repository.OrganizationRepository, auditRepo repository.AuditRepository, refreshRepo repository.RefreshTokenRepository, apiKeyRepo repository.APIKeyRepository, cache *PermissionCache, statusCache *UserStatusCache, keyCache *APIKeyCache, throttle *LoginThrottle) UserService {
+	return &userService{userRepo: userRepo, roleRepo: roleRepo, orgRepo: orgRepo, auditRepo: auditRepo, refreshRepo: refreshRepo, apiKeyRepo: apiKeyRepo, cache: cache, statusCache: statusCache, keyCache: keyCache, throttle: throttle}
+}
+
+// SearchUsers lists members of the active organization whose username or email
+// contains the query
+func (s *userService) SearchUsers(ctx context.Context, search domain.UserSearch) (*domain.UserPage, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return nil, ErrNoActiveOrg
+	}
+
+	if search.Page < 1 {
+		search.Page = 1
+	}
+	if search.PageSize < 1 {
+		search.PageSize = defaultUserPageSize
+	}
+	if search.PageSize > maxUserPageSize {
+		search.PageSize = maxUserPageSize
+	}
+
+	users, total, err := s.userRepo.Search(ctx, orgID, search)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserPage{Users: users, Total: total, Page: search.Page, PageSize: search.PageSize}, nil
+}
+
+// ListRoles returns a user's direct role assignments in the active organization
+func (s *userService) ListRoles(ctx context.Context, userID int64) ([]domain.RoleAssignment, error) {
+	orgID, err := s.memberOrg(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.ListRoleAssignments(ctx, userID, orgID)
+}
+
+// AssignRole assigns a role to a user in the active organization, optionally for
+// a limited window. Re-assigning a role replaces its window.
+func (s *userService) AssignRole(ctx context.Context, actorID, userID int64, req domain.AssignRoleRequest) error {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return ErrNoActiveOrg
+	}
+
+	assignment := domain.RoleAssignment{
+		UserID:     userID,
+		RoleID:     req.RoleID,
+		OrgID:      orgID,
+		ValidFrom:  time.Now(),
+		ValidUntil: req.ValidUntil,
+	}
+	if req.ValidFrom != nil {
+		assignment.ValidFrom = *req.ValidFrom
+	}
+	if assignment.ValidUntil != nil && !assignment.ValidUntil.After(assignment.ValidFrom) {
+		return ErrInvalidValidity
+	}
+
+	if _, err := s.roleRepo.FindByID(ctx, req.RoleID); err != nil {
+		return err
+	}
+	member, err := s.orgRepo.IsMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotOrgMember
+	}
+
+	if err := s.userRepo.AssignTemporaryRole(ctx, assignment); err != nil {
+		return err
+	}
+	s.cache.InvalidateUser(userID)
+
+	event := &domain.AuditEvent{
+		Type:        domain.AuditRoleAssigned,
+		ActorUserID: &actorID,
+		UserID:      &userID,
+		OrgID:       &orgID,
+		Details: map[string]interface{}{
+			"role_id":     assignment.RoleID,
+			"valid_from":  assignment.ValidFrom,
+			"valid_until": assignment.ValidUntil,
+		},
+	}
+	return s.auditRepo.Record(ctx, event)
+}
+
+// RevokeRole removes a direct role assignment in the active organization.
+// Roles held through a group are revoked on the group instead.
+func (s *userService) RevokeRole(ctx context.Context, actorID, userID, roleID int64) error {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return ErrNoActiveOrg
+	}
+
+	if err := s.userRepo.RevokeRole(ctx, userID, roleID, orgID); err != nil {
+		return err
+	}
+	s.cache.InvalidateUser(userID)
+
+	event := &domain.AuditEvent{
+		Type:        domain.AuditRoleRevoked,
+		ActorUserID: &actorID,
+		UserID:      &userID,
+		OrgID:       &orgID,
+		Details:     map[string]interface{}{"role_id": roleID},
+	}
+	return s.auditRepo.Record(ctx, event)
+}
+
+// Deactivate locks a user out: Login refuses them and their existing tokens stop working
+func (s *userService) Deactivate(ctx context.Context, actorID, userID int64) error {
+	if actorID == userID {
+		return newValidationError("you cannot deactivate your own account")
+	}
+	return s.setActive(ctx, actorID, userID, false)
+}
+
+// Activate reverses Deactivate
+func (s *userService) Activate(ctx context.Context, actorID, userID int64) error {
+	return s.setActive(ctx, actorID, userID, true)
+}
+
+// RevokeSessions logs a user out everywhere: every access token issued so far
+// is rejected, and every refresh token and API key is revoked. Sessions are not tied to an
+// organization, so callers must hold a platform grant.
+func (s *userService) RevokeSessions(ctx context.Context, actorID, userID int64) error {
+	if err := s.userRepo.RevokeSessions(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	s.statusCache.Invalidate(userID)
+	if err := s.refreshRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := deleteAPIKeys(ctx, s.apiKeyRepo, s.keyCache, userID); err != nil {
+		return err
+	}
+
+	event := &domain.AuditEvent{
+		Type:        domain.AuditSessionsRevoked,
+		ActorUserID: &actorID,
+		UserID:      &userID,
+	}
+	return s.auditRepo.Record(ctx, event)
+}
+
+// Unlock lifts a lockout caused by failed logins or wrong verification codes,
+// and any backoff, before it expires. The lockout covers every organization, so callers must hold a
+// platform grant.
+func (s *userService) Unlock(ctx context.Context, actorID, userID int64) error {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.throttle.ResetMFA(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.throttle.ResetAccount(ctx, userID); err != nil {
+		return err
+	}
+
+	event := &domain.AuditEvent{
+		Type:        domain.AuditAccountUnlocked,
+		ActorUserID: &actorID,
+		UserID:      &userID,
+	}
+	return s.auditRepo.Record(ctx, event)
+}
+
+// setActive changes the account in every organization, so callers must hold a
+// platform grant
+func (s *userService) setActive(ctx context.Context, actorID, userID int64, active bool) error {
+	if err := s.userRepo.SetActive(ctx, userID, active); err != nil {
+		return err
+	}
+	s.statusCache.Invalidate(userID)
+
+	eventType := domain.AuditUserDeactivated
+	if active {
+		eventType = domain.AuditUserActivated
+	}
+	event := &domain.AuditEvent{
+		Type:        eventType,
+		ActorUserID: &actorID,
+		UserID:      &userID,
+	}
+	return s.auditRepo.Record(ctx, event)
+}
+
+// memberOrg returns the active organization, or repository.ErrNotFound if the
+// user is not a member of it, so admins cannot reach users of other organizations
+func (s *userService) memberOrg(ctx context.Context, userID int64) (int64, error) {
+	orgID, ok := OrgIDFromContext(ctx)
+	if !ok {
+		return 0, ErrNoActiveOrg
+	}
+	member, err := s.orgRepo.IsMember(ctx, orgID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !member {
+		return 0, repository.ErrNotFound
+	}
+	return orgID, nil
+}
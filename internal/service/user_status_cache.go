@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"rbac/internal/repository"
+	"sync"
+	"time"
+)
+
+// UserStatus is the per-user state checked on every authenticated request
+type UserStatus struct {
+	Active            bool
+	SessionsRevokedAt *time.Time // Tokens issued at or before this are rejected
+}
+
+// UserStatusCache remembers each user's UserStatus, so AuthMiddleware does
+// not need a database round trip on every request. UserService invalidates it
+// on deactivation and session revocation, so both take effect immediately on
+// this instance and within the TTL elsewhere.
+//
+// A nil *UserStatusCache is valid and caches nothing.
+type UserStatusCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[int64]userStatusEntry
+	// generation changes on every invalidation, as in PermissionCache
+	generation uint64
+}
+
+type userStatusEntry struct {
+	status    UserStatus
+	expiresAt time.Time
+}
+
+// NewUserStatusCache creates a cache holding at most maxEntries users for ttl each.
+// It returns nil (caching disabled) if ttl or maxEntries is not positive.
+func NewUserStatusCache(ttl time.Duration, maxEntries int) *UserStatusCache {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &UserStatusCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[int64]userStatusEntry),
+	}
+}
+
+// Get returns the cached status and the current generation, which must be
+// passed back to Set after a miss.
+func (c *UserStatusCache) Get(userID int64) (UserStatus, uint64, bool) {
+	if c == nil {
+		return UserStatus{}, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return UserStatus{}, c.generation, false
+	}
+	return entry.status, c.generation, true
+}
+
+// Set stores a status loaded at generation. It is a no-op if the cache was
+// invalidated since then.
+func (c *UserStatusCache) Set(userID int64, status UserStatus, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if _, ok := c.entries[userID]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictExpired()
+		if len(c.entries) >= c.maxEntries {
+			// Entries are cheap to reload; start over rather than track recency
+			c.entries = make(map[int64]userStatusEntry)
+		}
+	}
+	c.entries[userID] = userStatusEntry{status: status, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Invalidate drops a user's cached status
+func (c *UserStatusCache) Invalidate(userID int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	delete(c.entries, userID)
+}
+
+// loadUserStatus returns a user's status through the cache, failing with
+// ErrAccountDeactivated if the user is inactive or gone
+func loadUserStatus(ctx context.Context, userRepo repository.UserRepository, cache *UserStatusCache, userID int64) (UserStatus, error) {
+	status, generation, ok := cache.Get(userID)
+	if !ok {
+		user, err := userRepo.FindByID(ctx, userID)
+		if err == repository.ErrNotFound {
+			return UserStatus{}, ErrAccountDeactivated
+		}
+		if err != nil {
+			return UserStatus{}, err
+		}
+		status = UserStatus{Active: user.IsActive, SessionsRevokedAt: user.SessionsRevokedAt}
+		cache.Set(userID, status, generation)
+	}
+	if !status.Active {
+		return UserStatus{}, ErrAccountDeactivated
+	}
+	return status, nil
+}
+
+func (c *UserStatusCache) evictExpired() {
+	now := time.Now()
+	for id, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Action token purposes. The purpose is the token's audience, so a token
+// issued for one action is refused by the others and as an access token.
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+// ActionClaims are the claims of a token mailed to a user to confirm an action
+type ActionClaims struct {
+	// Binding fingerprints the state the token was issued for, such as the
+	// email address to verify; the token is refused once that state changes
+	Binding string `json:"bnd"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the user the token was issued to
+func (c *ActionClaims) UserID() (int64, error) {
+	return strconv.ParseInt(c.Subject, 10, 64)
+}
+
+// ActionBinding fingerprints state for ActionClaims.Binding without putting
+// the state itself in the token
+func ActionBinding(state string) string {
+	return HashToken(state)[:32]
+}
+
+// GenerateActionToken signs a token for purpose, valid for ttl. Its jti lets
+// the caller make it single-use.
+func GenerateActionToken(purpose string, userID int64, binding string, key *SigningKey, ttl time.Duration) (string, error) {
+	jti, err := RandomID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := &ActionClaims{
+		Binding: binding,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatInt(userID, 10),
+			Audience:  jwt.ClaimStrings{purpose},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			Issuer:    "go-rbac-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// ValidateActionToken verifies a token issued for purpose
+func ValidateActionToken(tokenString, purpose string, lookup KeyLookup) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+	if _, err := parseSigned(tokenString, claims, lookup); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(purpose, true) || claims.ExpiresAt == nil || claims.ID == "" {
+		return nil, fmt.Errorf("token was not issued for %s", purpose)
+	}
+	return claims, nil
+}
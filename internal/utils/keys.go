@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported token signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// rsaKeyBits is the modulus size of generated RS256 keys
+const rsaKeyBits = 2048
+
+// KeyEncryptionKeySize is the size of the AES-256 key that seals stored private keys
+const KeyEncryptionKeySize = 32
+
+// sealedKeyPrefix marks the format of SealPrivateKey's output
+const sealedKeyPrefix = "aesgcm:"
+
+// SigningKey is an asymmetric key that signs access tokens. Tokens name the
+// key in their kid header; verifiers only ever need the public half.
+type SigningKey struct {
+	KID       string
+	Algorithm string
+	Private   crypto.Signer
+	CreatedAt time.Time
+	ExpiresAt time.Time // After this the key no longer verifies tokens
+}
+
+// GenerateSigningKey creates a new key for alg with a random kid
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := RandomID()
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{KID: kid, Algorithm: alg, Private: private}, nil
+}
+
+// MarshalPrivateKey encodes a private key as PKCS#8 PEM
+func MarshalPrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey decodes a PKCS#8 PEM private key written by MarshalPrivateKey
+func ParsePrivateKey(pemData string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// SealPrivateKey encrypts a PEM private key with AES-256-GCM under kek, so
+// that reading the store is not enough to sign tokens. The kid is
+// authenticated along with it: a sealed key copied to another row does not open.
+func SealPrivateKey(pemData string, kek []byte, kid string) (string, error) {
+	aead, err := newKeyAEAD(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(pemData), []byte(kid))
+	return sealedKeyPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenPrivateKey decrypts a key sealed by SealPrivateKey for kid
+func OpenPrivateKey(sealed string, kek []byte, kid string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedKeyPrefix)
+	if !ok {
+		return "", fmt.Errorf("private key is not sealed")
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed private key: %w", err)
+	}
+	aead, err := newKeyAEAD(kek)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed sealed private key")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	pemData, err := aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt private key (wrong key encryption key?): %w", err)
+	}
+	return string(pemData), nil
+}
+
+func newKeyAEAD(kek []byte) (cipher.AEAD, error) {
+	if len(kek) != KeyEncryptionKeySize {
+		return nil, fmt.Errorf("key encryption key must be %d bytes", KeyEncryptionKeySize)
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// JWK is the public half of a signing key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, as served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK returns the key's public half as a JWK
+func (k *SigningKey) PublicJWK() JWK {
+	jwk := JWK{Use: "sig", Alg: k.Algorithm, Kid: k.KID}
+	switch pub := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// Uncompressed point: 0x04 || X || Y, with fixed-width coordinates
+		point, err := pub.Bytes()
+		if err != nil {
+			return jwk
+		}
+		size := (len(point) - 1) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(point[1 : 1+size])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(point[1+size:])
+	}
+	return jwk
+}
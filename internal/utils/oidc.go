@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// IDTokenClaims defines the claims of an OIDC ID token
+type IDTokenClaims struct {
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	AMR               []string         `json:"amr,omitempty"`
+	OrgID             int64            `json:"org_id"`
+	Roles             []string         `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken signs an ID token with key, naming it in the kid header
+func GenerateIDToken(claims *IDTokenClaims, key *SigningKey) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// VerifyPKCE checks a code_verifier against an S256 code_challenge (RFC 7636)
+func VerifyPKCE(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"matching verifier", verifier, challenge, true},
+		{"other verifier", verifier + "x", challenge, false},
+		{"empty verifier", "", challenge, false},
+		{"empty challenge", verifier, "", false},
+		{"both empty", "", "", false},
+		// Only S256 is supported: the verifier itself is not a valid challenge
+		{"plain method", verifier, verifier, false},
+		{"padded challenge", verifier, challenge + "=", false},
+		// The challenge is base64url; the same digest in standard base64 does not match
+		{"base64url challenge", "verifier-0xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", "_Dg2pmvKxUq5RHOX1Tx-IgoK6Fm_XKwju5TVZ-Q65_U", true},
+		{"standard base64 challenge", "verifier-0xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", "/Dg2pmvKxUq5RHOX1Tx+IgoK6Fm/XKwju5TVZ+Q65/U", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+			t.Errorf("%s: VerifyPKCE(%q, %q) = %v, want %v", tt.name, tt.verifier, tt.challenge, got, tt.want)
+		}
+	}
+}
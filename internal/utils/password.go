@@ -1,15 +1,141 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-// HashPassword generates a bcrypt hash for a given password
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	argon2Prefix     = "$argon2id$"
+	// bcryptMaxPasswordBytes is the longest password bcrypt accepts
+	bcryptMaxPasswordBytes = 72
+	// argon2MaxPasswordBytes bounds the work a single (attacker-chosen) password can cause
+	argon2MaxPasswordBytes = 1024
+)
+
+// PasswordHashParams selects the algorithm and cost of new password hashes.
+// Stored hashes describe their own parameters, so these can change at any time.
+type PasswordHashParams struct {
+	Algorithm         string // HashArgon2id or HashBcrypt
+	BcryptCost        int
+	Argon2MemoryKiB   uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// MaxPasswordBytes is the longest password the algorithm can hash
+func (p PasswordHashParams) MaxPasswordBytes() int {
+	if p.Algorithm == HashBcrypt {
+		return bcryptMaxPasswordBytes
+	}
+	return argon2MaxPasswordBytes
 }
 
-// CheckPasswordHash compares a password with its hash
+// HashPassword hashes a password with the given parameters. Argon2id hashes
+// use the PHC string format: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashPassword(password string, params PasswordHashParams) (string, error) {
+	if params.Algorithm == HashBcrypt {
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
+		return string(bytes), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Argon2Iterations, params.Argon2MemoryKiB, params.Argon2Parallelism, argon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		params.Argon2MemoryKiB, params.Argon2Iterations, params.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPasswordHash compares a password with a bcrypt or Argon2id hash
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
\ No newline at end of file
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		return err == nil
+	}
+
+	stored, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), stored.salt, stored.iterations, stored.memory, stored.parallelism, uint32(len(stored.key)))
+	return subtle.ConstantTimeCompare(key, stored.key) == 1
+}
+
+// NeedsRehash reports whether a stored hash is weaker than params asks for:
+// bcrypt when Argon2id is wanted, or a lower cost of the same algorithm.
+// An Argon2id hash is kept when the params ask for bcrypt.
+func NeedsRehash(hash string, params PasswordHashParams) bool {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		if params.Algorithm == HashArgon2id {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost < params.BcryptCost
+	}
+
+	if params.Algorithm != HashArgon2id {
+		return false
+	}
+	stored, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false // Not ours to fix; it cannot be verified either
+	}
+	return stored.memory < params.Argon2MemoryKiB ||
+		stored.iterations < params.Argon2Iterations ||
+		stored.parallelism < params.Argon2Parallelism ||
+		len(stored.key) < argon2KeyLength
+}
+
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// parseArgon2Hash decodes a hash in the format written by HashPassword
+func parseArgon2Hash(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("malformed argon2id key: %w", err)
+	}
+	if len(h.key) == 0 || h.iterations == 0 || h.parallelism == 0 {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+	return &h, nil
+}
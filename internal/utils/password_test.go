@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters: the tests check the format and comparisons, not the cost
+var testArgon2Params = PasswordHashParams{Algorithm: HashArgon2id, Argon2MemoryKiB: 64, Argon2Iterations: 2, Argon2Parallelism: 1}
+
+func testHash(t *testing.T, params PasswordHashParams) string {
+	t.Helper()
+	hash, err := HashPassword("correct horse", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHashPasswordArgon2idFormat(t *testing.T) {
+	hash := testHash(t, testArgon2Params)
+	format := regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=2,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`)
+	if !format.MatchString(hash) {
+		t.Fatalf("HashPassword = %q, want the PHC format", hash)
+	}
+	if again := testHash(t, testArgon2Params); again == hash {
+		t.Error("hashing twice produced the same hash; the salt is not random")
+	}
+}
+
+func TestCheckPasswordHash(t *testing.T) {
+	argon2Hash := testHash(t, testArgon2Params)
+	bcryptHash := testHash(t, PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost})
+	parts := strings.Split(argon2Hash, "$")
+	withParams := func(params string) string {
+		return strings.Join([]string{"", parts[1], parts[2], params, parts[4], parts[5]}, "$")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{"argon2id", "correct horse", argon2Hash, true},
+		{"argon2id wrong password", "correct horse!", argon2Hash, false},
+		{"argon2id empty password", "", argon2Hash, false},
+		{"bcrypt", "correct horse", bcryptHash, true},
+		{"bcrypt wrong password", "correct horse!", bcryptHash, false},
+		// The parameters are part of the hash: changing them changes the key
+		{"argon2id other iterations", "correct horse", withParams("m=64,t=3,p=1"), false},
+		{"argon2id other version", "correct horse", strings.Replace(argon2Hash, "v=19", "v=16", 1), false},
+		{"argon2id missing part", "correct horse", strings.Join(parts[:5], "$"), false},
+		{"argon2id zero iterations", "correct horse", withParams("m=64,t=0,p=1"), false},
+		{"argon2id zero parallelism", "correct horse", withParams("m=64,t=2,p=0"), false},
+		{"argon2id malformed parameters", "correct horse", withParams("m=64;t=2;p=1"), false},
+		{"argon2id salt not base64", "correct horse", strings.Replace(argon2Hash, parts[4], "!!!", 1), false},
+		{"argon2id empty key", "correct horse", strings.TrimSuffix(argon2Hash, parts[5]), false},
+		{"empty hash", "correct horse", "", false},
+	}
+	for _, tt := range tests {
+		if got := CheckPasswordHash(tt.password, tt.hash); got != tt.want {
+			t.Errorf("%s: CheckPasswordHash(%q, %q) = %v, want %v", tt.name, tt.password, tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2Hash := testHash(t, testArgon2Params)
+	bcryptHash := testHash(t, PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost})
+	argon2With := func(change func(p *PasswordHashParams)) PasswordHashParams {
+		params := testArgon2Params
+		change(&params)
+		return params
+	}
+
+	tests := []struct {
+		name   string
+		hash   string
+		params PasswordHashParams
+		want   bool
+	}{
+		{"argon2id same parameters", argon2Hash, testArgon2Params, false},
+		{"argon2id more memory wanted", argon2Hash, argon2With(func(p *PasswordHashParams) { p.Argon2MemoryKiB = 128 }), true},
+		{"argon2id more iterations wanted", argon2Hash, argon2With(func(p *PasswordHashParams) { p.Argon2Iterations = 3 }), true},
+		{"argon2id more parallelism wanted", argon2Hash, argon2With(func(p *PasswordHashParams) { p.Argon2Parallelism = 2 }), true},
+		{"argon2id less memory wanted", argon2Hash, argon2With(func(p *PasswordHashParams) { p.Argon2MemoryKiB = 32 }), false},
+		{"argon2id when bcrypt wanted", argon2Hash, PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: 12}, false},
+		{"argon2id malformed", "$argon2id$v=19$garbage", testArgon2Params, false},
+		{"bcrypt when argon2id wanted", bcryptHash, testArgon2Params, true},
+		{"bcrypt same cost", bcryptHash, PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost}, false},
+		{"bcrypt higher cost wanted", bcryptHash, PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost + 1}, true},
+		{"bcrypt lower cost wanted", bcryptHash, PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost - 1}, false},
+		{"bcrypt malformed", "not a hash", PasswordHashParams{Algorithm: HashBcrypt, BcryptCost: 12}, false},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(tt.hash, tt.params); got != tt.want {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaxPasswordBytes(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      int
+	}{
+		{HashBcrypt, 72},
+		{HashArgon2id, 1024},
+		{"", 1024},
+	}
+	for _, tt := range tests {
+		if got := (PasswordHashParams{Algorithm: tt.algorithm}).MaxPasswordBytes(); got != tt.want {
+			t.Errorf("MaxPasswordBytes for %q = %d, want %d", tt.algorithm, got, tt.want)
+		}
+	}
+}
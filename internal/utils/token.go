@@ -1,42 +1,160 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// Claims defines the JWT claims
+// Authentication method references (RFC 8176) recorded in the amr claim
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
+)
+
+// ClientTokenType is the typ header of access tokens issued to OIDC clients
+// (RFC 9068), which keeps them apart from ID tokens for the same audience
+const ClientTokenType = "at+jwt"
+
+// Claims defines the JWT claims. RegisteredClaims.ID carries the jti that
+// revocation is keyed on.
 type Claims struct {
-	UserID int64 `json:"user_id"`
+	UserID int64    `json:"user_id"`
+	OrgID  int64    `json:"org_id"` // Active organization; permissions are evaluated in it
+	AMR    []string `json:"amr,omitempty"`
+	// Set only on tokens issued to OIDC clients, whose audience is the client
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token
-func GenerateToken(userID int64, secret string, expirationHours int64) (string, error) {
-	expirationTime := time.Now().Add(time.Hour * time.Duration(expirationHours))
+// HasScope reports whether the token was granted scope
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// MFA reports whether the session behind the token completed multi-factor authentication
+func (c *Claims) MFA() bool {
+	return slices.Contains(c.AMR, AMRMFA)
+}
+
+// SessionAMR returns the amr claim for a password login, with or without a second factor
+func SessionAMR(mfa bool) []string {
+	if mfa {
+		return []string{AMRPassword, AMROTP, AMRMFA}
+	}
+	return []string{AMRPassword}
+}
+
+// KeyLookup returns the key that verifies tokens carrying kid
+type KeyLookup func(kid string) (*SigningKey, error)
+
+// GenerateToken generates a new JWT access token valid for ttl, signed with key
+// and naming it in the kid header. amr may be nil for non-interactive clients.
+func GenerateToken(userID, orgID int64, amr []string, key *SigningKey, ttl time.Duration) (string, error) {
+	jti, err := RandomID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := &Claims{
 		UserID: userID,
+		OrgID:  orgID,
+		AMR:    amr,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			Issuer:    "go-rbac-api",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// GenerateClientToken generates an access token for an OIDC client, issued at
+// issuedAt and valid for ttl. Its audience is the client and it carries the
+// granted scope, so ValidateToken refuses it and it only works where
+// ValidateClientToken is used. The caller picks the jti (see RandomID) and the
+// issue time so it can record both beforehand.
+func GenerateClientToken(userID, orgID int64, amr []string, clientID, scope, jti string, key *SigningKey, issuedAt time.Time, ttl time.Duration) (string, error) {
+	claims := &Claims{
+		UserID:   userID,
+		OrgID:    orgID,
+		AMR:      amr,
+		ClientID: clientID,
+		Scope:    scope,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
 			Issuer:    "go-rbac-api",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	token.Header["typ"] = ClientTokenType
+	return token.SignedString(key.Private)
+}
+
+// ValidateToken validates a JWT token against the key named by its kid header.
+// The token's alg must match the key's algorithm.
+func ValidateToken(tokenString string, lookup KeyLookup) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := parseSigned(tokenString, claims, lookup); err != nil {
+		return nil, err
+	}
+	// ID tokens, action tokens and client access tokens are signed with the
+	// same keys but always carry an audience; first-party access tokens never do
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("not an access token")
+	}
+
+	return claims, nil
 }
 
-// ValidateToken validates a JWT token
-func ValidateToken(tokenString string, secret string) (*Claims, error) {
+// ValidateClientToken validates an access token issued to an OIDC client
+func ValidateClientToken(tokenString string, lookup KeyLookup) (*Claims, error) {
 	claims := &Claims{}
+	token, err := parseSigned(tokenString, claims, lookup)
+	if err != nil {
+		return nil, err
+	}
+	if typ, _ := token.Header["typ"].(string); typ != ClientTokenType || claims.ClientID == "" || !claims.VerifyAudience(claims.ClientID, true) {
+		return nil, fmt.Errorf("not a client access token")
+	}
+
+	return claims, nil
+}
+
+// parseSigned verifies a token signed with one of our keys and fills claims
+func parseSigned(tokenString string, claims jwt.Claims, lookup KeyLookup) (*jwt.Token, error) {
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token has no kid")
+		}
+		key, err := lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(secret), nil
-	})
+		return key.Private.Public(), nil
+	}, jwt.WithValidMethods([]string{AlgRS256, AlgES256}))
 
 	if err != nil {
 		return nil, err
@@ -45,6 +163,30 @@ func ValidateToken(tokenString string, secret string) (*Claims, error) {
 	if !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	return token, nil
+}
 
-	return claims, nil
-}
\ No newline at end of file
+// RandomID returns 128 random bits as 32 hex characters, for token and family IDs
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateOpaqueToken returns a random URL-safe token with 256 bits of entropy
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of an opaque token, for storage and lookup.
+// Opaque tokens are random, so a fast unsalted hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/base32"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// Secret of the RFC 6238 appendix B vectors, "12345678901234567890" in base32
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B (SHA1), cut to the last six digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		step, ok := ValidateTOTP(rfcTOTPSecret, tt.code, at)
+		if !ok {
+			t.Errorf("ValidateTOTP(%q) at %d failed, want success", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / 30; step != want {
+			t.Errorf("ValidateTOTP(%q) at %d step = %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// "287082" is the code for step 1 (seconds 30 to 59)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		unix   int64
+		want   bool
+	}{
+		{"current step", rfcTOTPSecret, "287082", 45, true},
+		{"one step late", rfcTOTPSecret, "287082", 75, true},
+		{"one step early", rfcTOTPSecret, "287082", 15, true},
+		{"two steps late", rfcTOTPSecret, "287082", 95, false},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", 45, true},
+		{"wrong code", rfcTOTPSecret, "287083", 45, false},
+		{"too short", rfcTOTPSecret, "28708", 45, false},
+		{"too long", rfcTOTPSecret, "2870820", 45, false},
+		{"empty code", rfcTOTPSecret, "", 45, false},
+		{"secret not base32", "not base32!", "287082", 45, false},
+	}
+	for _, tt := range tests {
+		if _, got := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("%s: ValidateTOTP(%q, %q) at %d = %v, want %v", tt.name, tt.secret, tt.code, tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret is %d bytes, want 20", len(key))
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		code, err := GenerateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Errorf("GenerateRecoveryCode() = %q, want the form xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("GenerateRecoveryCode() returned %q twice", code)
+		}
+		seen[code] = true
+		if got := NormalizeRecoveryCode(code); got != code[:5]+code[6:] {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q", code, got)
+		}
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"k4xq7-m2wzp", "k4xq7m2wzp"},
+		{"K4XQ7-M2WZP", "k4xq7m2wzp"},
+		{"k4xq7m2wzp", "k4xq7m2wzp"},
+		{" k4xq7 m2wzp ", "k4xq7m2wzp"},
+		{"k4-xq-7m-2w-zp", "k4xq7m2wzp"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
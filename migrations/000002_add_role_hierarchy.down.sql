@@ -0,0 +1,2 @@
+ALTER TABLE roles DROP FOREIGN KEY fk_roles_parent;
+ALTER TABLE roles DROP COLUMN parent_id;
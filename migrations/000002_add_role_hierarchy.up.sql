@@ -0,0 +1,4 @@
+-- roles.parent_id: a role inherits every permission of its parent (admin -> editor -> user)
+ALTER TABLE roles
+    ADD COLUMN parent_id BIGINT NULL AFTER name,
+    ADD CONSTRAINT fk_roles_parent FOREIGN KEY (parent_id) REFERENCES roles(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE role_permissions DROP COLUMN scope;
+ALTER TABLE users DROP FOREIGN KEY fk_users_team;
+ALTER TABLE users DROP COLUMN team_id;
+DROP TABLE IF EXISTS teams;
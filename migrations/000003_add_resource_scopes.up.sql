@@ -0,0 +1,17 @@
+-- teams: users can belong to one team; "team" scoped grants match resources owned by teammates
+CREATE TABLE teams (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users
+    ADD COLUMN team_id BIGINT NULL AFTER password_hash,
+    ADD CONSTRAINT fk_users_team FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL;
+
+-- role_permissions.scope: which resources a grant applies to
+--   any  - every resource
+--   own  - only resources created by the user
+--   team - only resources created by a member of the user's team
+ALTER TABLE role_permissions
+    ADD COLUMN scope ENUM('any', 'own', 'team') NOT NULL DEFAULT 'any';
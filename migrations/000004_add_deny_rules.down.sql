@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_permissions;
+ALTER TABLE role_permissions DROP COLUMN effect;
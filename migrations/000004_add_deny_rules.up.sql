@@ -0,0 +1,14 @@
+-- role_permissions.effect: a deny grant overrides every allow the user gets from any role
+ALTER TABLE role_permissions
+    ADD COLUMN effect ENUM('allow', 'deny') NOT NULL DEFAULT 'allow';
+
+-- user_permissions: grants and denies attached directly to a user, bypassing roles
+CREATE TABLE user_permissions (
+    user_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    effect ENUM('allow', 'deny') NOT NULL DEFAULT 'deny',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, permission_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- role_permissions.condition_expr: optional ABAC condition evaluated at check time,
+-- e.g. "env.hour >= 9 && env.hour < 17" or "resource.price < 1000".
+-- The grant only applies when the condition holds. (CONDITION is reserved in MySQL.)
+ALTER TABLE role_permissions
+    ADD COLUMN condition_expr TEXT NULL;
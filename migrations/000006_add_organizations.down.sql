@@ -0,0 +1,17 @@
+ALTER TABLE products DROP FOREIGN KEY fk_products_org;
+ALTER TABLE products DROP COLUMN org_id;
+
+ALTER TABLE user_permissions DROP FOREIGN KEY fk_user_permissions_org;
+ALTER TABLE user_permissions
+    DROP PRIMARY KEY,
+    DROP COLUMN org_id,
+    ADD PRIMARY KEY (user_id, permission_id);
+
+ALTER TABLE user_roles DROP FOREIGN KEY fk_user_roles_org;
+ALTER TABLE user_roles
+    DROP PRIMARY KEY,
+    DROP COLUMN org_id,
+    ADD PRIMARY KEY (user_id, role_id);
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
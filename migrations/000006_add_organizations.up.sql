@@ -0,0 +1,39 @@
+-- organizations: tenants. Roles are shared definitions, but assignments and products belong to one org.
+CREATE TABLE organizations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing data moves into a default organization
+INSERT INTO organizations (id, name) VALUES (1, 'default');
+
+-- organization_members: which organizations a user can switch into
+CREATE TABLE organization_members (
+    org_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id),
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO organization_members (org_id, user_id) SELECT 1, id FROM users;
+
+-- user_roles / user_permissions: assignments are per organization
+ALTER TABLE user_roles
+    ADD COLUMN org_id BIGINT NOT NULL DEFAULT 1 AFTER role_id,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (user_id, org_id, role_id),
+    ADD CONSTRAINT fk_user_roles_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE;
+
+ALTER TABLE user_permissions
+    ADD COLUMN org_id BIGINT NOT NULL DEFAULT 1 AFTER permission_id,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (user_id, org_id, permission_id),
+    ADD CONSTRAINT fk_user_permissions_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE;
+
+-- products: every product belongs to an organization
+ALTER TABLE products
+    ADD COLUMN org_id BIGINT NOT NULL DEFAULT 1 AFTER id,
+    ADD CONSTRAINT fk_products_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS audit_events;
+ALTER TABLE user_roles
+    DROP INDEX idx_user_roles_valid_until,
+    DROP COLUMN valid_until,
+    DROP COLUMN valid_from;
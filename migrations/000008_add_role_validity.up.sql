@@ -0,0 +1,19 @@
+-- user_roles validity window: an assignment only counts between valid_from and valid_until.
+-- A NULL valid_until never expires.
+ALTER TABLE user_roles
+    ADD COLUMN valid_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN valid_until TIMESTAMP NULL DEFAULT NULL,
+    ADD INDEX idx_user_roles_valid_until (valid_until);
+
+-- audit_events: security-relevant changes, e.g. an expired role assignment being removed
+CREATE TABLE audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    actor_user_id BIGINT NULL,
+    user_id BIGINT NULL,
+    org_id BIGINT NULL,
+    details JSON NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_audit_events_user (user_id, created_at),
+    INDEX idx_audit_events_type (event_type, created_at)
+);
@@ -0,0 +1,12 @@
+-- platform_permissions: grants that are not tied to any organization. They
+-- guard state every tenant shares, such as role and permission definitions,
+-- which organization admins must not be able to change. There is no API to
+-- grant them; operators insert rows directly.
+CREATE TABLE platform_permissions (
+    user_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, permission_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- users.is_active: deactivated users cannot log in, and their existing tokens are refused
+ALTER TABLE users
+    ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE AFTER team_id,
+    ADD COLUMN deactivated_at TIMESTAMP NULL DEFAULT NULL AFTER is_active;
@@ -0,0 +1,14 @@
+-- revoked_tokens: access tokens revoked before expiry (e.g. by logout), keyed by jti.
+-- Rows are only needed until the token would have expired anyway.
+CREATE TABLE revoked_tokens (
+    jti CHAR(32) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_revoked_tokens_created (created_at),
+    INDEX idx_revoked_tokens_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- users.sessions_revoked_at: tokens issued at or before this time are rejected
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMP NULL DEFAULT NULL AFTER deactivated_at;
@@ -0,0 +1,12 @@
+-- signing_keys: asymmetric keys that sign access tokens, shared by every instance.
+-- The newest key signs; older keys keep verifying until expires_at, which leaves
+-- an overlap window for tokens signed just before a rotation. Private keys are
+-- sealed with a key encryption key that is not in the database.
+CREATE TABLE signing_keys (
+    kid CHAR(32) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    sealed_private_key TEXT NOT NULL, -- AES-256-GCM sealed PKCS#8 PEM
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    INDEX idx_signing_keys_expires (expires_at)
+);
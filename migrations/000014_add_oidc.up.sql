@@ -0,0 +1,29 @@
+-- oauth_clients: applications registered to sign users in through OIDC.
+-- Public clients (e.g. SPAs) have no secret and rely on PKCE alone.
+CREATE TABLE oauth_clients (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id CHAR(32) NOT NULL UNIQUE,
+    client_secret_hash CHAR(64) NULL,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris JSON NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- oauth_authorization_codes: short-lived, single-use codes from /authorize, stored hashed
+CREATE TABLE oauth_authorization_codes (
+    code_hash CHAR(64) PRIMARY KEY,
+    client_id CHAR(32) NOT NULL,
+    user_id BIGINT NOT NULL,
+    org_id BIGINT NOT NULL,
+    redirect_uri VARCHAR(2048) NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    nonce VARCHAR(255) NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    auth_time TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    access_token_jti VARCHAR(32) NULL, -- issued at exchange, revoked if the code is presented again
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
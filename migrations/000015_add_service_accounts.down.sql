@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS service_accounts;
+DELETE FROM users WHERE is_service_account = TRUE;
+ALTER TABLE users
+    MODIFY email VARCHAR(255) NOT NULL,
+    DROP COLUMN is_service_account;
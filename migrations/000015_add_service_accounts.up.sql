@@ -0,0 +1,19 @@
+-- Service accounts are users without a password (or email) that authenticate
+-- with a client ID and secret, so they get roles and memberships like any user
+ALTER TABLE users
+    ADD COLUMN is_service_account BOOLEAN NOT NULL DEFAULT FALSE AFTER team_id,
+    MODIFY email VARCHAR(255) NULL;
+
+-- service_accounts: client credentials of service account users
+CREATE TABLE service_accounts (
+    user_id BIGINT PRIMARY KEY,
+    org_id BIGINT NOT NULL,
+    client_id CHAR(32) NOT NULL UNIQUE,
+    client_secret_hash CHAR(64) NOT NULL,
+    description VARCHAR(255) NULL,
+    created_by BIGINT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,17 @@
+-- api_keys: long-lived personal keys, stored as SHA-256 hashes. A key acts as
+-- its owner in one organization, optionally limited to some permissions.
+CREATE TABLE api_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    org_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL, -- First characters of the key, to recognise it in listings
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    permissions JSON NULL, -- NULL: all of the owner's permissions
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_api_keys_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
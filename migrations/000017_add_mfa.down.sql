@@ -0,0 +1,6 @@
+ALTER TABLE oauth_authorization_codes DROP COLUMN mfa;
+ALTER TABLE refresh_tokens DROP COLUMN mfa;
+ALTER TABLE roles DROP COLUMN requires_mfa;
+DROP TABLE IF EXISTS mfa_challenges;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
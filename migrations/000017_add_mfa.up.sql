@@ -0,0 +1,42 @@
+-- user_totp: TOTP enrollments. enabled_at stays NULL until the user confirms
+-- a first code, so an abandoned enrollment never blocks login.
+CREATE TABLE user_totp (
+    user_id BIGINT PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL, -- Base32 shared secret
+    enabled_at TIMESTAMP NULL DEFAULT NULL,
+    last_used_step BIGINT NULL, -- Time step of the last accepted code; each code is accepted once
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- mfa_recovery_codes: one-time codes for a lost authenticator, stored as SHA-256 hashes
+CREATE TABLE mfa_recovery_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_mfa_recovery_code (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- mfa_challenges: issued by the password step of login and exchanged, with a
+-- code, for tokens by the second step
+CREATE TABLE mfa_challenges (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL,
+    org_id BIGINT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+-- Grants of a role that requires MFA only apply to sessions that completed it
+ALTER TABLE roles ADD COLUMN requires_mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Whether the login behind a session completed MFA, carried across refreshes
+ALTER TABLE refresh_tokens ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE oauth_authorization_codes ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+-- NULL until the user follows a verification link (or a password reset link,
+-- which proves the same thing)
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL DEFAULT NULL AFTER email;
+
+-- used_action_tokens: jti of password reset and email verification tokens that
+-- have been used. The tokens are signed and never stored; a row makes one single-use.
+CREATE TABLE used_action_tokens (
+    jti CHAR(32) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
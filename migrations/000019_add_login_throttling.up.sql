@@ -0,0 +1,10 @@
+-- login_failures: recent failed logins per account and per client IP. A row
+-- drives the backoff between attempts and, past a threshold, a temporary lockout.
+CREATE TABLE login_failures (
+    scope VARCHAR(16) NOT NULL, -- 'account' (subject is the user ID) or 'ip'
+    subject VARCHAR(64) NOT NULL,
+    failures INT NOT NULL DEFAULT 0, -- Since the last lockout or success
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (scope, subject)
+);